@@ -150,6 +150,18 @@ func (b *Buffer) Clone() *Buffer {
 	return newBuf
 }
 
+// Uses reports whether the buffer reads the reader.
+func (b *Buffer) Uses(r io.ReaderAt) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, rr := range b.rrs {
+		if rr.r == r {
+			return true
+		}
+	}
+	return false
+}
+
 // Insert inserts a byte at the specific position.
 func (b *Buffer) Insert(offset int64, c byte) {
 	b.mu.Lock()
@@ -188,6 +200,71 @@ func (b *Buffer) Insert(offset int64, c byte) {
 	panic("buffer.Buffer.Insert: unreachable")
 }
 
+// InsertBytes inserts the bytes at the specific position.
+func (b *Buffer) InsertBytes(offset int64, bs []byte) {
+	if len(bs) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.insertRange(offset, newBytesReader(append([]byte{}, bs...)), int64(len(bs)))
+}
+
+// InsertReader inserts the first n bytes of the reader at the specific
+// position. The bytes are read lazily from the reader.
+func (b *Buffer) InsertReader(offset int64, r readAtSeeker, n int64) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.insertRange(offset, r, n)
+}
+
+// ReplaceReader overwrites the bytes at the specific position with the first
+// n bytes of the reader, without changing the length of the buffer.
+func (b *Buffer) ReplaceReader(offset int64, r readAtSeeker, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if l, err := b.len(); err == nil {
+		n = mathutil.MinInt64(n, l-offset)
+	}
+	if n <= 0 {
+		return
+	}
+	b.deleteRange(offset, offset+n)
+	b.insertRange(offset, r, n)
+}
+
+func (b *Buffer) insertRange(offset int64, r readAtSeeker, n int64) {
+	for i, rr := range b.rrs {
+		if offset >= rr.max {
+			continue
+		}
+		b.rrs = append(b.rrs, readerRange{})
+		b.rrs = append(b.rrs, readerRange{})
+		copy(b.rrs[i+2:], b.rrs[i:])
+		b.rrs[i] = readerRange{rr.r, rr.min, offset, rr.diff}
+		b.rrs[i+1] = readerRange{r, offset, offset + n, -offset}
+		b.rrs[i+2] = readerRange{b.clone(rr.r), offset + n, addMax(rr.max, n), rr.diff - n}
+		for i = i + 3; i < len(b.rrs); i++ {
+			b.rrs[i].min += n
+			b.rrs[i].max = addMax(b.rrs[i].max, n)
+			b.rrs[i].diff -= n
+		}
+		b.cleanup()
+		return
+	}
+	panic("buffer.Buffer.insertRange: unreachable")
+}
+
+func addMax(max, n int64) int64 {
+	if max == math.MaxInt64 {
+		return max
+	}
+	return max + n
+}
+
 // Replace replaces a byte at the specific position.
 func (b *Buffer) Replace(offset int64, c byte) {
 	b.mu.Lock()
@@ -266,6 +343,41 @@ func (b *Buffer) Delete(offset int64) {
 	panic("buffer.Buffer.Delete: unreachable")
 }
 
+// DeleteRange deletes the bytes from the offset to the end offset (exclusive).
+func (b *Buffer) DeleteRange(from, to int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deleteRange(from, to)
+}
+
+func (b *Buffer) deleteRange(from, to int64) {
+	if l, err := b.len(); err == nil {
+		to = mathutil.MinInt64(to, l)
+	}
+	if from >= to {
+		return
+	}
+	n := to - from
+	rrs := make([]readerRange, 0, len(b.rrs)+1)
+	for _, rr := range b.rrs {
+		if rr.max <= from {
+			rrs = append(rrs, rr)
+			continue
+		}
+		if rr.min < from {
+			rrs = append(rrs, readerRange{rr.r, rr.min, from, rr.diff})
+			rr.r, rr.min = b.clone(rr.r), to
+		}
+		if rr.max == math.MaxInt64 {
+			rrs = append(rrs, readerRange{rr.r, mathutil.MaxInt64(rr.min, to) - n, rr.max, rr.diff + n})
+		} else if rr.max > to {
+			rrs = append(rrs, readerRange{rr.r, mathutil.MaxInt64(rr.min, to) - n, rr.max - n, rr.diff + n})
+		}
+	}
+	b.rrs = rrs
+	b.cleanup()
+}
+
 func (b *Buffer) clone(r readAtSeeker) readAtSeeker {
 	switch br := r.(type) {
 	case *bytesReader:
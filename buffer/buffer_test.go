@@ -378,3 +378,128 @@ func TestBufferDelete(t *testing.T) {
 		t.Errorf("len(b.rrs) should be 4 but got: %d", len(b.rrs))
 	}
 }
+
+func TestBufferDeleteRange(t *testing.T) {
+	b := NewBuffer(strings.NewReader("0123456789abcdef"))
+
+	tests := []struct {
+		from     int64
+		to       int64
+		b        byte
+		expected string
+		len      int64
+	}{
+		{2, 5, 0x00, "0156789abcdef", 13},
+		{0, 1, 0x00, "156789abcdef", 12},
+		{3, 3, 0x00, "156789abcdef", 12},
+		{3, 0, 0x41, "156A789abcdef", 13},
+		{2, 5, 0x00, "1589abcdef", 10},
+		{1, 2, 0x42, "1B589abcdef", 11},
+		{0, 3, 0x00, "89abcdef", 8},
+		{6, 20, 0x00, "89abcd", 6},
+		{0, 6, 0x00, "", 0},
+	}
+
+	for _, test := range tests {
+		if test.b == 0x00 {
+			b.DeleteRange(test.from, test.to)
+		} else {
+			b.Insert(test.from, test.b)
+		}
+		p := make([]byte, 20)
+		n, err := b.ReadAt(p, 0)
+		if err != nil && err != io.EOF {
+			t.Errorf("err should be nil or io.EOF but got: %v", err)
+		}
+		if string(p[:n]) != test.expected {
+			t.Errorf("p should be %q but got: %q", test.expected, string(p[:n]))
+		}
+		l, err := b.Len()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if l != test.len {
+			t.Errorf("l should be %d but got: %d", test.len, l)
+		}
+	}
+}
+
+func TestBufferInsertBytes(t *testing.T) {
+	b := NewBuffer(strings.NewReader("0123456789abcdef"))
+
+	tests := []struct {
+		offset   int64
+		bs       string
+		expected string
+		len      int64
+	}{
+		{0, "xy", "xy0123456789abcdef", 18},
+		{5, "ABC", "xy012ABC3456789abcdef", 21},
+		{8, "D", "xy012ABCD3456789abcdef", 22},
+		{3, "", "xy012ABCD3456789abcdef", 22},
+		{22, "EF", "xy012ABCD3456789abcdefEF", 24},
+		{7, "G", "xy012ABGCD3456789abcdefEF", 25},
+	}
+
+	for _, test := range tests {
+		b.InsertBytes(test.offset, []byte(test.bs))
+		p := make([]byte, 30)
+		n, err := b.ReadAt(p, 0)
+		if err != nil && err != io.EOF {
+			t.Errorf("err should be nil or io.EOF but got: %v", err)
+		}
+		if string(p[:n]) != test.expected {
+			t.Errorf("p should be %q but got: %q", test.expected, string(p[:n]))
+		}
+		l, err := b.Len()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if l != test.len {
+			t.Errorf("l should be %d but got: %d", test.len, l)
+		}
+	}
+}
+
+func TestBufferInsertReader(t *testing.T) {
+	b := NewBuffer(strings.NewReader("0123456789abcdef"))
+
+	tests := []struct {
+		offset   int64
+		r        string
+		n        int64
+		replace  bool
+		expected string
+		len      int64
+	}{
+		{4, "xyz", 3, false, "0123xyz456789abcdef", 19},
+		{0, "ABCDEF", 2, false, "AB0123xyz456789abcdef", 21},
+		{21, "XY", 2, false, "AB0123xyz456789abcdefXY", 23},
+		{3, "ghij", 4, true, "AB0ghijyz456789abcdefXY", 23},
+		{20, "KLMN", 4, true, "AB0ghijyz456789abcdeKLM", 23},
+		{5, "", 0, false, "AB0ghijyz456789abcdeKLM", 23},
+	}
+
+	for _, test := range tests {
+		if test.replace {
+			b.ReplaceReader(test.offset, strings.NewReader(test.r), test.n)
+		} else {
+			b.InsertReader(test.offset, strings.NewReader(test.r), test.n)
+		}
+		p := make([]byte, 30)
+		n, err := b.ReadAt(p, 0)
+		if err != nil && err != io.EOF {
+			t.Errorf("err should be nil or io.EOF but got: %v", err)
+		}
+		if string(p[:n]) != test.expected {
+			t.Errorf("p should be %q but got: %q", test.expected, string(p[:n]))
+		}
+		l, err := b.Len()
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if l != test.len {
+			t.Errorf("l should be %d but got: %d", test.len, l)
+		}
+	}
+}
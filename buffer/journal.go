@@ -0,0 +1,182 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The journal records the pieces of the buffer. A piece of the base reader is
+// recorded by the offset and the length, a piece of the other files by the
+// name, the size and the modification time of the file as well, and the other
+// pieces by the bytes, so the journal stays small for large files.
+const (
+	journalBase byte = 'f' // offset, length
+	journalTail byte = 'e' // offset, the rest of the base reader
+	journalFile byte = 'r' // name, size, modification time, offset, length
+	journalData byte = 'b' // length, bytes
+)
+
+// WriteJournal writes the pieces of the buffer to the writer. The pieces of
+// the base reader, which is the reader the buffer is created with, and of the
+// other files are written as the references to them.
+func (b *Buffer) WriteJournal(w io.Writer, base readAtSeeker) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeRecord := func(typ byte, xs ...int64) {
+		bw.WriteByte(typ)
+		for _, x := range xs {
+			bw.Write(buf[:binary.PutUvarint(buf, uint64(x))])
+		}
+	}
+	for _, rr := range b.rrs {
+		if rr.r == base {
+			if rr.max == math.MaxInt64 {
+				writeRecord(journalTail, rr.min+rr.diff)
+			} else {
+				writeRecord(journalBase, rr.min+rr.diff, rr.max-rr.min)
+			}
+			continue
+		}
+		n := rr.max - rr.min
+		if rr.max == math.MaxInt64 {
+			l, err := rr.r.Seek(0, io.SeekEnd)
+			if err != nil {
+				return err
+			}
+			n = l - rr.diff - rr.min
+		}
+		if f, ok := rr.r.(*os.File); ok && filepath.IsAbs(f.Name()) {
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			writeRecord(journalFile, int64(len(f.Name())))
+			bw.WriteString(f.Name())
+			bw.Write(buf[:binary.PutUvarint(buf, uint64(info.Size()))])
+			bw.Write(buf[:binary.PutVarint(buf, info.ModTime().UnixNano())])
+			bw.Write(buf[:binary.PutUvarint(buf, uint64(rr.min+rr.diff))])
+			bw.Write(buf[:binary.PutUvarint(buf, uint64(n))])
+			continue
+		}
+		writeRecord(journalData, n)
+		if _, err := io.Copy(bw, io.NewSectionReader(rr.r, rr.min+rr.diff, n)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadJournal restores the buffer from the journal and the base reader. The
+// other files are opened with the open function, which should check that the
+// file has the recorded size and modification time.
+func ReadJournal(
+	r io.Reader, base readAtSeeker,
+	open func(name string, size, modTime int64) (*os.File, error),
+) (*Buffer, error) {
+	br := bufio.NewReader(r)
+	var rrs []readerRange
+	var offset int64
+	readInt := func() (int64, error) {
+		x, err := binary.ReadUvarint(br)
+		if err != nil || x > math.MaxInt64 {
+			return 0, errors.New("buffer.ReadJournal: invalid journal")
+		}
+		return int64(x), nil
+	}
+	for {
+		typ, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case journalBase, journalTail:
+			pos, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			if typ == journalTail {
+				rrs = append(rrs, readerRange{base, offset, math.MaxInt64, pos - offset})
+				break
+			}
+			n, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, readerRange{base, offset, offset + n, pos - offset})
+			offset += n
+		case journalFile:
+			l, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			var name bytes.Buffer
+			if _, err := io.CopyN(&name, br, l); err == io.EOF {
+				return nil, errors.New("buffer.ReadJournal: invalid journal")
+			} else if err != nil {
+				return nil, err
+			}
+			size, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			modTime, err := binary.ReadVarint(br)
+			if err != nil {
+				return nil, errors.New("buffer.ReadJournal: invalid journal")
+			}
+			pos, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			n, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			if open == nil {
+				return nil, fmt.Errorf("buffer.ReadJournal: cannot open %s", name.String())
+			}
+			f, err := open(name.String(), size, modTime)
+			if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, readerRange{f, offset, offset + n, pos - offset})
+			offset += n
+		case journalData:
+			n, err := readInt()
+			if err != nil {
+				return nil, err
+			}
+			// the length is not trusted to allocate, since the journal can be broken
+			var buf bytes.Buffer
+			if _, err := io.CopyN(&buf, br, n); err == io.EOF {
+				return nil, errors.New("buffer.ReadJournal: invalid journal")
+			} else if err != nil {
+				return nil, err
+			}
+			rrs = append(rrs, readerRange{newBytesReader(buf.Bytes()), offset, offset + n, -offset})
+			offset += n
+		default:
+			return nil, fmt.Errorf("buffer.ReadJournal: invalid record: %q", typ)
+		}
+		if rrs[len(rrs)-1].max == math.MaxInt64 {
+			break
+		}
+	}
+	if len(rrs) == 0 || rrs[len(rrs)-1].max != math.MaxInt64 {
+		rrs = append(rrs, readerRange{newBytesReader(nil), offset, math.MaxInt64, -offset})
+	}
+	b := &Buffer{rrs: rrs, mu: new(sync.Mutex)}
+	b.cleanup()
+	return b, nil
+}
@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBufferJournal(t *testing.T) {
+	base := strings.NewReader(strings.Repeat("0123456789", 100))
+	b := NewBuffer(base)
+	b.Insert(3, 0x41)
+	b.Insert(4, 0x42)
+	b.Replace(10, 0x43)
+	b.DeleteRange(20, 990)
+	b.InsertReader(25, strings.NewReader("xyz"), 3)
+	b.InsertBytes(0, []byte("head"))
+
+	var journal bytes.Buffer
+	if err := b.WriteJournal(&journal, base); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if journal.Len() > 40 {
+		t.Errorf("journal should be small but got %d bytes", journal.Len())
+	}
+	r, err := ReadJournal(&journal, base, nil)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	expected, _ := ioutil.ReadAll(b)
+	got, _ := ioutil.ReadAll(r)
+	if string(got) != string(expected) {
+		t.Errorf("buffer should be %q but got %q", string(expected), string(got))
+	}
+	l, err := r.Len()
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if l != int64(len(expected)) {
+		t.Errorf("length should be %d but got %d", len(expected), l)
+	}
+
+	r.Insert(l, 0x44)
+	r.Seek(0, io.SeekStart)
+	got, _ = ioutil.ReadAll(r)
+	if !strings.HasSuffix(string(got), "789D") {
+		t.Errorf("buffer should be editable after restored but got %q", string(got))
+	}
+
+	for _, journal := range []string{"x", "f\x01", "b\x05abc", "b\xff\xff\xff\xff\xff\xff\xff\xff\x7fabc"} {
+		if _, err := ReadJournal(strings.NewReader(journal), base, nil); err == nil {
+			t.Errorf("err should not be nil for %q", journal)
+		}
+	}
+	r, err = ReadJournal(strings.NewReader("b\x03abc"), base, nil)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if got, _ := ioutil.ReadAll(r); string(got) != "abc" {
+		t.Errorf("buffer should be %q but got %q", "abc", string(got))
+	}
+}
+
+func TestBufferJournalFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "bed-test-buffer-journal-file")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString(strings.Repeat("abcdefghij", 100)); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	base := strings.NewReader("0123456789")
+	b := NewBuffer(base)
+	b.InsertReader(5, f, 1000)
+	b.DeleteRange(8, 1000)
+
+	var journal bytes.Buffer
+	if err := b.WriteJournal(&journal, base); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if journal.Len() > 2*len(f.Name())+40 {
+		t.Errorf("journal should refer to the file but got %d bytes", journal.Len())
+	}
+	bs := journal.Bytes()
+	if _, err := ReadJournal(bytes.NewReader(bs), base, nil); err == nil {
+		t.Errorf("err should not be nil without the open function")
+	}
+	var opened []string
+	var files []*os.File
+	r, err := ReadJournal(bytes.NewReader(bs), base, func(name string, size, modTime int64) (*os.File, error) {
+		opened = append(opened, name)
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if size != info.Size() || modTime != info.ModTime().UnixNano() {
+			t.Errorf("size and modification time should be recorded but got %d, %d", size, modTime)
+		}
+		f, err := os.Open(name)
+		if err == nil {
+			files = append(files, f)
+		}
+		return f, err
+	})
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if len(opened) != 2 || opened[0] != f.Name() || opened[1] != f.Name() {
+		t.Errorf("the file should be opened but got %v", opened)
+	}
+	if got, _ := ioutil.ReadAll(r); string(got) != "01234abcfghij56789" {
+		t.Errorf("buffer should be %q but got %q", "01234abcfghij56789", string(got))
+	}
+}
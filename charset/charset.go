@@ -0,0 +1,142 @@
+package charset
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// Charset decodes characters from bytes.
+type Charset struct {
+	Name   string
+	decode func([]byte) (rune, int)
+}
+
+var charsets = []*Charset{
+	{"ascii", decodeASCII},
+	{"utf-8", decodeUTF8},
+	{"utf-16le", decodeUTF16LE},
+	{"utf-16be", decodeUTF16BE},
+	{"latin-1", decodeLatin1},
+	{"shift_jis", multiByteDecoder(japanese.ShiftJIS, 2)},
+	{"euc-jp", multiByteDecoder(japanese.EUCJP, 3)},
+	{"cp037", decodeCP037},
+}
+
+var aliases = map[string]string{
+	"utf8":       "utf-8",
+	"utf-16":     "utf-16le",
+	"latin1":     "latin-1",
+	"iso-8859-1": "latin-1",
+	"sjis":       "shift_jis",
+	"eucjp":      "euc-jp",
+	"ebcdic":     "cp037",
+}
+
+// Lookup returns the charset of the name.
+func Lookup(name string) *Charset {
+	if n, ok := aliases[name]; ok {
+		name = n
+	}
+	for _, c := range charsets {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the charsets.
+func Names() []string {
+	names := make([]string, len(charsets))
+	for i, c := range charsets {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Decode decodes the first character of the bytes. It returns utf8.RuneError
+// and the size 1 when the bytes are invalid, or the size 0 for empty bytes.
+func (c *Charset) Decode(bs []byte) (rune, int) {
+	if len(bs) == 0 {
+		return utf8.RuneError, 0
+	}
+	return c.decode(bs)
+}
+
+// Align returns the offset where a character should start at or before the
+// offset, for the charset of fixed-width code units.
+func (c *Charset) Align(offset int64) int64 {
+	if c.Name == "utf-16le" || c.Name == "utf-16be" {
+		return offset &^ 1
+	}
+	return offset
+}
+
+func decodeASCII(bs []byte) (rune, int) {
+	if bs[0] < 0x80 {
+		return rune(bs[0]), 1
+	}
+	return utf8.RuneError, 1
+}
+
+func decodeUTF8(bs []byte) (rune, int) {
+	r, n := utf8.DecodeRune(bs)
+	if r == utf8.RuneError {
+		return r, 1
+	}
+	return r, n
+}
+
+func decodeUTF16(bs []byte, unit func([]byte) rune) (rune, int) {
+	if len(bs) < 2 {
+		return utf8.RuneError, 1
+	}
+	r := unit(bs)
+	if !utf16.IsSurrogate(r) {
+		return r, 2
+	}
+	if len(bs) < 4 {
+		return utf8.RuneError, 2
+	}
+	if r = utf16.DecodeRune(r, unit(bs[2:])); r == utf8.RuneError {
+		return r, 2
+	}
+	return r, 4
+}
+
+func decodeUTF16LE(bs []byte) (rune, int) {
+	return decodeUTF16(bs, func(bs []byte) rune { return rune(bs[0]) | rune(bs[1])<<8 })
+}
+
+func decodeUTF16BE(bs []byte) (rune, int) {
+	return decodeUTF16(bs, func(bs []byte) rune { return rune(bs[0])<<8 | rune(bs[1]) })
+}
+
+func decodeLatin1(bs []byte) (rune, int) {
+	return rune(bs[0]), 1
+}
+
+func decodeCP037(bs []byte) (rune, int) {
+	return charmap.CodePage037.DecodeByte(bs[0]), 1
+}
+
+// multiByteDecoder creates a decoder which tries the bytes up to the maximum
+// length of a character.
+func multiByteDecoder(e encoding.Encoding, maxSize int) func([]byte) (rune, int) {
+	return func(bs []byte) (rune, int) {
+		for n := 1; n <= maxSize && n <= len(bs); n++ {
+			out, err := e.NewDecoder().Bytes(bs[:n])
+			if err != nil {
+				break
+			}
+			if r, m := utf8.DecodeRune(out); r != utf8.RuneError && m == len(out) {
+				return r, n
+			}
+		}
+		return utf8.RuneError, 1
+	}
+}
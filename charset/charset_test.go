@@ -0,0 +1,64 @@
+package charset
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names() {
+		if c := Lookup(name); c == nil || c.Name != name {
+			t.Errorf("Lookup(%q) should return the charset but got %v", name, c)
+		}
+	}
+	if c := Lookup("sjis"); c == nil || c.Name != "shift_jis" {
+		t.Errorf("Lookup(%q) should return shift_jis but got %v", "sjis", c)
+	}
+	if c := Lookup("utf-32"); c != nil {
+		t.Errorf("Lookup(%q) should return nil but got %v", "utf-32", c)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, testCase := range []struct {
+		name  string
+		bytes string
+		r     rune
+		size  int
+	}{
+		{"ascii", "A", 'A', 1},
+		{"ascii", "\xe3", utf8.RuneError, 1},
+		{"utf-8", "\xe3\x81\x82", 'あ', 3},
+		{"utf-8", "\xe3\x81", utf8.RuneError, 1},
+		{"utf-16le", "\x42\x30", 'あ', 2},
+		{"utf-16be", "\x30\x42", 'あ', 2},
+		{"utf-16le", "\x3d\xd8\x00\xde", '😀', 4},
+		{"utf-16be", "\xd8\x3d\xde\x00", '😀', 4},
+		{"utf-16be", "\xd8\x3d\x00\x41", utf8.RuneError, 2},
+		{"utf-16le", "\x41", utf8.RuneError, 1},
+		{"latin-1", "\xe9", 'é', 1},
+		{"shift_jis", "\x82\xa0", 'あ', 2},
+		{"shift_jis", "\xb1", 'ｱ', 1},
+		{"shift_jis", "A\x82", 'A', 1},
+		{"euc-jp", "\xa4\xa2", 'あ', 2},
+		{"euc-jp", "\x8f\xb0\xa1", '丂', 3},
+		{"cp037", "\xc1", 'A', 1},
+		{"cp037", "\x40", ' ', 1},
+		{"utf-8", "", utf8.RuneError, 0},
+	} {
+		r, size := Lookup(testCase.name).Decode([]byte(testCase.bytes))
+		if r != testCase.r || size != testCase.size {
+			t.Errorf("Decode(%q) with %s should be (%q, %d) but got (%q, %d)",
+				testCase.bytes, testCase.name, testCase.r, testCase.size, r, size)
+		}
+	}
+}
+
+func TestAlign(t *testing.T) {
+	if offset := Lookup("utf-16le").Align(5); offset != 4 {
+		t.Errorf("Align(5) should be 4 but got %d", offset)
+	}
+	if offset := Lookup("utf-8").Align(5); offset != 5 {
+		t.Errorf("Align(5) should be 5 but got %d", offset)
+	}
+}
@@ -0,0 +1,50 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/itchyny/bed/shell"
+)
+
+// Encode formats the bytes to copy to the clipboard; as a hexadecimal string
+// or the raw text.
+func Encode(bs []byte, format string) []byte {
+	if format == "raw" {
+		return bs
+	}
+	return []byte(hex.EncodeToString(bs))
+}
+
+// Decode interprets the contents of the clipboard. The contents consisting
+// of pairs of hexadecimal digits, which may be separated by white spaces, are
+// decoded to the bytes, and other contents are pasted as they are.
+func Decode(bs []byte) []byte {
+	s := strings.Join(strings.Fields(string(bs)), "")
+	if s == "" || len(s)%2 != 0 {
+		return bs
+	}
+	ds, err := hex.DecodeString(s)
+	if err != nil {
+		return bs
+	}
+	return ds
+}
+
+// OSC52 returns the escape sequence to set the clipboard of the terminal.
+func OSC52(bs []byte) []byte {
+	return []byte("\x1b]52;c;" + base64.StdEncoding.EncodeToString(bs) + "\x07")
+}
+
+// Copy copies the bytes to the clipboard with the external command.
+func Copy(cmd string, bs []byte) error {
+	_, err := shell.Run(cmd, bytes.NewReader(bs))
+	return err
+}
+
+// Paste returns the contents of the clipboard with the external command.
+func Paste(cmd string) ([]byte, error) {
+	return shell.Run(cmd, nil)
+}
@@ -0,0 +1,61 @@
+package clipboard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	if got := string(Encode([]byte("\x00Hi\xff"), "hex")); got != "004869ff" {
+		t.Errorf("Encode should return %q but got %q", "004869ff", got)
+	}
+	if got := string(Encode([]byte("\x00Hi\xff"), "raw")); got != "\x00Hi\xff" {
+		t.Errorf("Encode should return %q but got %q", "\x00Hi\xff", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		contents, expected string
+	}{
+		{"004869ff", "\x00Hi\xff"},
+		{"00 48 69 FF\n", "\x00Hi\xff"},
+		{"Hello", "Hello"},
+		{"abc", "abc"},
+		{"", ""},
+	} {
+		if got := string(Decode([]byte(tc.contents))); got != tc.expected {
+			t.Errorf("Decode(%q) should return %q but got %q", tc.contents, tc.expected, got)
+		}
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	if got := string(OSC52([]byte("4869"))); got != "\x1b]52;c;NDg2OQ==\x07" {
+		t.Errorf("OSC52 should return %q but got %q", "\x1b]52;c;NDg2OQ==\x07", got)
+	}
+}
+
+func TestCopyPaste(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "clipboard")
+	if err := Copy("cat > "+f, []byte("4869")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := Paste("cat " + f)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "4869" {
+		t.Errorf("Paste should return %q but got %q", "4869", string(bs))
+	}
+	if _, err := Paste("echo error >&2; exit 1"); err == nil || err.Error() != "echo error >&2; exit 1: error" {
+		t.Errorf("err should be %q but got: %v", "echo error >&2; exit 1: error", err)
+	}
+}
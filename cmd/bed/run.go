@@ -1,8 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
 
 	"github.com/itchyny/bed/cmdline"
 	"github.com/itchyny/bed/editor"
@@ -11,10 +15,29 @@ import (
 )
 
 func run(args []string) int {
-	if len(args) > 2 {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-u file] [-N] [-f] [-r] [file]\n", name)
+		fs.PrintDefaults()
+	}
+	config := fs.String("u", "", "read the commands from the file instead of ~/.config/bed/bedrc")
+	noConfig := fs.Bool("N", false, "skip reading the configuration file")
+	follow := fs.Bool("f", false, "follow the file growing like tail -f")
+	recovery := fs.Bool("r", false, "recover the changes of the file from the swap file")
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 1 {
 		fmt.Fprintf(os.Stderr, "%s: too many files\n", name)
 		return 1
 	}
+	if *recovery && fs.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "%s: a file is required to recover\n", name)
+		return 1
+	}
 	editor := editor.NewEditor(
 		tui.NewTui(), window.NewManager(), cmdline.NewCmdline(),
 	)
@@ -22,8 +45,25 @@ func run(args []string) int {
 		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 		return 1
 	}
-	if len(args) > 1 {
-		if err := editor.Open(args[1]); err != nil {
+	if !*noConfig {
+		if err := loadConfig(editor, *config); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		}
+	}
+	if *follow {
+		if err := editor.Command("set follow"); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		}
+	}
+	if *recovery {
+		if err := editor.Recover(fs.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			return 1
+		}
+	} else if fs.NArg() > 0 {
+		if err := editor.Open(fs.Arg(0)); err != nil {
 			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
 			return 1
 		}
@@ -43,3 +83,27 @@ func run(args []string) int {
 	}
 	return 0
 }
+
+// loadConfig reads the configuration file specified by the -u flag, or the
+// default one if it exists. The value NONE skips reading the file.
+func loadConfig(editor *editor.Editor, config string) error {
+	if config == "NONE" {
+		return nil
+	}
+	if config != "" {
+		return editor.LoadConfig(config)
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	config = filepath.Join(dir, name, "bedrc")
+	if _, err := os.Stat(config); err != nil {
+		return nil
+	}
+	return editor.LoadConfig(config)
+}
@@ -166,7 +166,7 @@ func (c *Cmdline) insert(ch rune) {
 }
 
 func (c *Cmdline) complete(forward bool) {
-	cmd, _, prefix, arg, err := parse(c.cmdline)
+	cmd, _, _, prefix, arg, err := parse(c.cmdline)
 	if err != nil {
 		c.completor.clear()
 		return
@@ -179,13 +179,13 @@ func (c *Cmdline) complete(forward bool) {
 func (c *Cmdline) execute() {
 	switch c.typ {
 	case ':':
-		cmd, r, _, arg, err := parse(c.cmdline)
+		e, err := c.Parse(string(c.cmdline))
 		if err != nil {
 			c.eventCh <- event.Event{Type: event.Error, Error: err}
 			return
 		}
-		if cmd.name != "" {
-			c.eventCh <- event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Arg: arg}
+		if e.CmdName != "" {
+			c.eventCh <- e
 		}
 	case '/':
 		c.eventCh <- event.Event{Type: event.ExecuteSearch, Arg: string(c.cmdline), Rune: '/'}
@@ -196,6 +196,16 @@ func (c *Cmdline) execute() {
 	}
 }
 
+// Parse parses the command line to the event. The event has no command name
+// for an empty command line.
+func (c *Cmdline) Parse(cmdline string) (event.Event, error) {
+	cmd, r, bang, _, arg, err := parse([]rune(cmdline))
+	if err != nil {
+		return event.Event{}, err
+	}
+	return event.Event{Type: cmd.eventType, Range: r, CmdName: cmd.name, Bang: bang, Arg: arg}, nil
+}
+
 // Get returns the current state of cmdline.
 func (c *Cmdline) Get() ([]rune, int, []string, int) {
 	c.mu.Lock()
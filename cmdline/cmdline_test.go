@@ -377,8 +377,6 @@ func TestCmdlineExecuteWriteQuit(t *testing.T) {
 		{"wq", "wq"},
 		{"x", "x[it]"},
 		{"xit", "x[it]"},
-		{"xa", "xa[ll]"},
-		{"xall", "xa[ll]"},
 	} {
 		c.clear()
 		c.cmdline = []rune(cmd.cmd)
@@ -394,6 +392,179 @@ func TestCmdlineExecuteWriteQuit(t *testing.T) {
 	}
 }
 
+func TestCmdlineExecuteWriteAll(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		name string
+		typ  event.Type
+	}{
+		{"sav foo", "sav[eas]", event.SaveAs},
+		{"saveas foo", "sav[eas]", event.SaveAs},
+		{"up", "up[date]", event.Update},
+		{"update", "up[date]", event.Update},
+		{"wa", "wa[ll]", event.WriteAll},
+		{"wall", "wa[ll]", event.WriteAll},
+		{"wqa", "wqa[ll]", event.WriteAllQuit},
+		{"xa", "xa[ll]", event.WriteAllQuit},
+		{"xall", "xa[ll]", event.WriteAllQuit},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d event with %q but got %d", cmd.typ, cmd.cmd, e.Type)
+		}
+	}
+}
+
+func TestCmdlineExecuteBang(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		typ  event.Type
+		bang bool
+		arg  string
+	}{
+		{"e", event.Edit, false, ""},
+		{"e!", event.Edit, true, ""},
+		{"edit! foo", event.Edit, true, "foo"},
+		{"w!", event.Write, true, ""},
+		{"checkt", event.CheckTime, false, ""},
+		{"!ls", event.Filter, false, "ls"},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d event with %q but got %d", cmd.typ, cmd.cmd, e.Type)
+		}
+		if e.Bang != cmd.bang {
+			t.Errorf("cmdline should emit event with bang %v with %q", cmd.bang, cmd.cmd)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
+func TestCmdlineExecuteFilter(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		typ  event.Type
+		name string
+		arg  string
+	}{
+		{"!ls", event.Filter, "!", "ls"},
+		{"'<,'>!gzip -d", event.Filter, "!", "gzip -d"},
+		{"0,$ ! openssl enc -d", event.Filter, "!", "openssl enc -d"},
+		{"r !date", event.Read, "r[ead]", "!date"},
+		{"read !date", event.Read, "r[ead]", "!date"},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d event with %q but got %d", cmd.typ, cmd.cmd, e.Type)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
+func TestCmdlineExecuteMatches(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		name string
+		typ  event.Type
+		arg  string
+	}{
+		{"count /abc/", "cou[nt]", event.CountMatches, "/abc/"},
+		{"'<,'>cou 0xcafe babe", "cou[nt]", event.CountMatches, "0xcafe babe"},
+		{"findall Hello", "findall", event.FindAll, "Hello"},
+		{"cn", "cn[ext]", event.NextMatch, ""},
+		{"cprev", "cp[revious]", event.PreviousMatch, ""},
+		{"cN", "cN[ext]", event.PreviousMatch, ""},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d but got %d with %q", cmd.typ, e.Type, cmd.cmd)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
+func TestCmdlineExecuteTemplate(t *testing.T) {
+	c := NewCmdline()
+	ch := make(chan event.Event, 1)
+	c.Init(ch, make(chan event.Event), make(chan struct{}))
+	for _, cmd := range []struct {
+		cmd  string
+		name string
+		typ  event.Type
+		arg  string
+	}{
+		{"template ~/png.bt 0x10", "templ[ate]", event.ApplyTemplate, "~/png.bt 0x10"},
+		{"templ", "templ[ate]", event.ApplyTemplate, ""},
+		{"field chunks[2].length", "fie[ld]", event.GotoField, "chunks[2].length"},
+		{"sections", "sec[tions]", event.ToggleFormat, ""},
+		{"chunks", "chu[nks]", event.ToggleFormat, ""},
+		{"goto section .text", "go[to]", event.GotoEntry, "section .text"},
+		{"'a,'bann red \"checksum field\"", "ann[otate]", event.Annotate, "red \"checksum field\""},
+		{"unann", "unann[otate]", event.Unannotate, ""},
+		{"annotations", "annotations", event.ToggleAnnotations, ""},
+		{"anext", "an[ext]", event.NextAnnotation, ""},
+		{"set columns=20 group=4", "se[t]", event.Set, "columns=20 group=4"},
+	} {
+		c.clear()
+		c.cmdline = []rune(cmd.cmd)
+		c.typ = ':'
+		c.execute()
+		e := <-ch
+		if e.CmdName != cmd.name {
+			t.Errorf("cmdline should report command name %q but got %q", cmd.name, e.CmdName)
+		}
+		if e.Type != cmd.typ {
+			t.Errorf("cmdline should emit %d but got %d with %q", cmd.typ, e.Type, cmd.cmd)
+		}
+		if e.Arg != cmd.arg {
+			t.Errorf("cmdline should emit event with arg %q but got %q", cmd.arg, e.Arg)
+		}
+	}
+}
+
 func TestCmdlineExecuteGoto(t *testing.T) {
 	c := NewCmdline()
 	ch := make(chan event.Event, 1)
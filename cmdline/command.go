@@ -13,6 +13,42 @@ var commands = []command{
 	{"vne[w]", event.Vnew},
 	{"winc[md]", event.Wincmd},
 
+	{"cou[nt]", event.CountMatches},
+	{"findall", event.FindAll},
+	{"cn[ext]", event.NextMatch},
+	{"cp[revious]", event.PreviousMatch},
+	{"cN[ext]", event.PreviousMatch},
+
+	{"insp[ector]", event.ToggleInspector},
+	{"templ[ate]", event.ApplyTemplate},
+	{"fie[ld]", event.GotoField},
+	{"sec[tions]", event.ToggleFormat},
+	{"chu[nks]", event.ToggleFormat},
+	{"ent[ries]", event.ToggleFormat},
+	{"go[to]", event.GotoEntry},
+
+	{"ann[otate]", event.Annotate},
+	{"unann[otate]", event.Unannotate},
+	{"annotations", event.ToggleAnnotations},
+	{"an[ext]", event.NextAnnotation},
+	{"ap[revious]", event.PreviousAnnotation},
+
+	{"se[t]", event.Set},
+	{"setl[ocal]", event.SetLocal},
+
+	{"map", event.Map},
+	{"nm[ap]", event.Map},
+	{"vm[ap]", event.Map},
+	{"im[ap]", event.Map},
+	{"no[remap]", event.Map},
+	{"nn[oremap]", event.Map},
+	{"vn[oremap]", event.Map},
+	{"ino[remap]", event.Map},
+	{"unm[ap]", event.Unmap},
+	{"nun[map]", event.Unmap},
+	{"vu[nmap]", event.Unmap},
+	{"iu[nmap]", event.Unmap},
+
 	{"u[ndo]", event.Undo},
 	{"red[o]", event.Redo},
 
@@ -23,5 +59,12 @@ var commands = []command{
 	{"w[rite]", event.Write},
 	{"wq", event.WriteQuit},
 	{"x[it]", event.WriteQuit},
-	{"xa[ll]", event.WriteQuit},
+	{"sav[eas]", event.SaveAs},
+	{"up[date]", event.Update},
+	{"wa[ll]", event.WriteAll},
+	{"wqa[ll]", event.WriteAllQuit},
+	{"xa[ll]", event.WriteAllQuit},
+	{"checkt[ime]", event.CheckTime},
+	{"r[ead]", event.Read},
+	{"!", event.Filter},
 }
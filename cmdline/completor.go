@@ -9,6 +9,7 @@ import (
 	"github.com/mitchellh/go-homedir"
 
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/option"
 )
 
 type completor struct {
@@ -32,10 +33,13 @@ func (c *completor) clear() {
 
 func (c *completor) complete(cmdline string, cmd command, prefix string, arg string, forward bool) string {
 	switch cmd.eventType {
-	case event.Edit, event.New, event.Vnew, event.Write:
+	case event.Edit, event.New, event.Vnew, event.Write, event.SaveAs, event.Update,
+		event.Read, event.ApplyTemplate:
 		return c.completeFilepaths(cmdline, prefix, arg, forward)
 	case event.Wincmd:
 		return c.completeWincmd(cmdline, prefix, arg, forward)
+	case event.Set, event.SetLocal:
+		return c.completeOptions(cmdline, prefix, arg, forward)
 	default:
 		c.results = nil
 		c.index = 0
@@ -69,6 +73,10 @@ func (c *completor) completeFilepaths(cmdline string, prefix string, arg string,
 	} else {
 		c.arg, c.results = c.listFileNames(arg)
 	}
+	return c.completeResults(cmdline, prefix, forward)
+}
+
+func (c *completor) completeResults(cmdline string, prefix string, forward bool) string {
 	if len(c.results) == 1 {
 		cmdline := prefix + c.arg + c.results[0]
 		c.results = nil
@@ -85,6 +93,41 @@ func (c *completor) completeFilepaths(cmdline string, prefix string, arg string,
 	return cmdline
 }
 
+func (c *completor) completeOptions(cmdline string, prefix string, arg string, forward bool) string {
+	if !strings.HasSuffix(prefix, " ") {
+		prefix += " "
+	}
+	if len(c.results) > 0 {
+		return c.completeNext(prefix, forward)
+	}
+	c.target = cmdline
+	c.index = 0
+	c.arg, c.results = listOptions(arg)
+	return c.completeResults(cmdline, prefix, forward)
+}
+
+// listOptions lists the option names, or the values after the equal sign,
+// to complete the last argument.
+func listOptions(arg string) (string, []string) {
+	i := strings.LastIndexByte(arg, ' ') + 1
+	arg, word := arg[:i], arg[i:]
+	candidates := option.Names()
+	if j := strings.IndexByte(word, '='); j >= 0 {
+		o := option.Lookup(word[:j])
+		if o == nil {
+			return arg, nil
+		}
+		arg, word, candidates = arg+word[:j+1], word[j+1:], o.Values
+	}
+	var targets []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			targets = append(targets, candidate)
+		}
+	}
+	return arg, targets
+}
+
 func (c *completor) listFileNames(arg string) (string, []string) {
 	var targets []string
 	separator := string(filepath.Separator)
@@ -7,7 +7,7 @@ import (
 func TestCompletorCompleteFilepath(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := "new "
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "new Gopkg.toml" {
 		t.Errorf("cmdline should be %q but got %q", "new Gopkg.toml", cmdline)
@@ -81,7 +81,7 @@ func TestCompletorCompleteFilepath(t *testing.T) {
 
 	c.clear()
 	cmdline = "new Gopkg.to"
-	cmd, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "new Gopkg.toml" {
 		t.Errorf("cmdline should be %q but got %q", "new Gopkg.toml", cmdline)
@@ -95,7 +95,7 @@ func TestCompletorCompleteFilepath(t *testing.T) {
 
 	c.clear()
 	cmdline = "edit"
-	cmd, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "edit Gopkg.toml" {
 		t.Errorf("cmdline should be %q but got %q", "edit Gopkg.toml", cmdline)
@@ -111,7 +111,7 @@ func TestCompletorCompleteFilepath(t *testing.T) {
 func TestCompletorCompleteFilepathKeepPrefix(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := " : : :  new   C"
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != " : : :  new   cmdline/" {
 		t.Errorf("cmdline should be %q but got %q", " : : :  new   cmdline/", cmdline)
@@ -144,7 +144,7 @@ func TestCompletorCompleteFilepathKeepPrefix(t *testing.T) {
 func TestCompletorCompleteFilepathHomedir(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := "vnew ~/"
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "vnew ~/example.txt" {
 		t.Errorf("cmdline should be %q but got %q", "vnew ~/example.txt", cmdline)
@@ -188,7 +188,7 @@ func TestCompletorCompleteFilepathHomedir(t *testing.T) {
 func TestCompletorCompleteFilepathHomedirDot(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := "vnew ~/."
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, false)
 	if cmdline != "vnew ~/.zshrc" {
 		t.Errorf("cmdline should be %q but got %q", "vnew ~/.zshrc", cmdline)
@@ -212,7 +212,7 @@ func TestCompletorCompleteFilepathHomedirDot(t *testing.T) {
 func TestCompletorCompleteFilepathRoot(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := "e /"
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "e /bin/" {
 		t.Errorf("cmdline should be %q but got %q", "e /bin/", cmdline)
@@ -234,7 +234,7 @@ func TestCompletorCompleteFilepathRoot(t *testing.T) {
 
 	cmdline = c.complete(cmdline, cmd, prefix, arg, false)
 	c.clear()
-	cmd, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "e /bin/cp" {
 		t.Errorf("cmdline should be %q but got %q", "e /bin/cp", cmdline)
@@ -247,7 +247,7 @@ func TestCompletorCompleteFilepathRoot(t *testing.T) {
 func TestCompletorCompleteWincmd(t *testing.T) {
 	c := newCompletor(&mockFilesystem{})
 	cmdline := "winc"
-	cmd, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "winc" {
 		t.Errorf("cmdline should be %q but got %q", "winc", cmdline)
@@ -277,7 +277,7 @@ func TestCompletorCompleteWincmd(t *testing.T) {
 	}
 
 	c.clear()
-	cmd, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
 	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
 	if cmdline != "winc J" {
 		t.Errorf("cmdline should be %q but got %q", "winc J", cmdline)
@@ -286,3 +286,47 @@ func TestCompletorCompleteWincmd(t *testing.T) {
 		t.Errorf("completion index should be %d but got %d", 0, c.index)
 	}
 }
+
+func TestCompletorCompleteOptions(t *testing.T) {
+	c := newCompletor(&mockFilesystem{})
+	cmdline := "set co"
+	cmd, _, _, prefix, arg, _ := parse([]rune(cmdline))
+	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
+	if cmdline != "set columns" {
+		t.Errorf("cmdline should be %q but got %q", "set columns", cmdline)
+	}
+
+	c.clear()
+	cmdline = "setl group=2 display="
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
+	if cmdline != "setl group=2 display=hex" {
+		t.Errorf("cmdline should be %q but got %q", "setl group=2 display=hex", cmdline)
+	}
+	for i := 0; i < 2; i++ {
+		cmdline = c.complete(cmdline, cmd, prefix, arg, true)
+	}
+	if cmdline != "setl group=2 display=octal" {
+		t.Errorf("cmdline should be %q but got %q", "setl group=2 display=octal", cmdline)
+	}
+	cmdline = c.complete(cmdline, cmd, prefix, arg, false)
+	if cmdline != "setl group=2 display=binary" {
+		t.Errorf("cmdline should be %q but got %q", "setl group=2 display=binary", cmdline)
+	}
+
+	c.clear()
+	cmdline = "set "
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmdline = c.complete(cmdline, cmd, prefix, arg, false)
+	if cmdline != "set timeoutlen" {
+		t.Errorf("cmdline should be %q but got %q", "set timeoutlen", cmdline)
+	}
+
+	c.clear()
+	cmdline = "set foo="
+	cmd, _, _, prefix, arg, _ = parse([]rune(cmdline))
+	cmdline = c.complete(cmdline, cmd, prefix, arg, true)
+	if cmdline != "set foo=" {
+		t.Errorf("cmdline should be %q but got %q", "set foo=", cmdline)
+	}
+}
@@ -8,38 +8,48 @@ import (
 	"github.com/itchyny/bed/event"
 )
 
-func parse(cmdline []rune) (command, *event.Range, string, string, error) {
+func parse(cmdline []rune) (command, *event.Range, bool, string, string, error) {
 	i, l := 0, len(cmdline)
 	for i < l && (unicode.IsSpace(cmdline[i]) || cmdline[i] == ':') {
 		i++
 	}
 	if i == l {
-		return command{}, nil, "", "", nil
+		return command{}, nil, false, "", "", nil
 	}
 	r, i := event.ParseRange(cmdline, i)
 	j := i
-	for j < l && !unicode.IsSpace(cmdline[j]) {
+	if j < l && cmdline[j] == '!' {
+		// the command of the filter follows without spaces
 		j++
+	} else {
+		for j < l && !unicode.IsSpace(cmdline[j]) {
+			j++
+		}
 	}
 	k := j
 	for k < l && unicode.IsSpace(cmdline[k]) {
 		k++
 	}
 	cmdName := string(cmdline[i:j])
+	// the command name followed by ! forces the command (:e!, :w!)
+	var bang bool
+	if len(cmdName) > 1 && strings.HasSuffix(cmdName, "!") {
+		cmdName, bang = cmdName[:len(cmdName)-1], true
+	}
 	for _, cmd := range commands {
 		if len(cmdName) == 0 || cmdName[0] != cmd.name[0] {
 			continue
 		}
 		for _, c := range expand(cmd.name) {
 			if cmdName == c {
-				return cmd, r, string(cmdline[:k]), strings.TrimSpace(string(cmdline[k:])), nil
+				return cmd, r, bang, string(cmdline[:k]), strings.TrimSpace(string(cmdline[k:])), nil
 			}
 		}
 	}
-	if len(strings.Fields(string(cmdline[k:]))) == 0 && r != nil {
-		return command{"goto", event.CursorGoto}, r, string(cmdline[:k]), "", nil
+	if len(strings.Fields(string(cmdline[k:]))) == 0 && r != nil && !bang {
+		return command{"goto", event.CursorGoto}, r, false, string(cmdline[:k]), "", nil
 	}
-	return command{}, nil, "", "", fmt.Errorf("unknown command: %s", string(cmdline))
+	return command{}, nil, false, "", "", fmt.Errorf("unknown command: %s", string(cmdline))
 }
 
 func expand(name string) []string {
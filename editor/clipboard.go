@@ -0,0 +1,73 @@
+package editor
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/itchyny/bed/clipboard"
+	"github.com/itchyny/bed/event"
+)
+
+// systemClipboard implements the clipboard of the registers. The bytes are
+// copied with the external command of the clipboardcopy option, or the OSC 52
+// escape sequence of the terminal, in the format of the clipboardformat
+// option. The bytes are pasted with the command of the clipboardpaste option.
+type systemClipboard struct {
+	tick    uint64 // accessed atomically, placed first for the alignment
+	ui      UI
+	wm      Manager
+	eventCh chan<- event.Event
+	mu      *sync.Mutex
+}
+
+// Copy copies the bytes asynchronously, because the window is locked while
+// yanking the bytes. The bytes copied before are skipped if the copy is not
+// started yet, so that the clipboard holds the bytes copied last.
+func (c *systemClipboard) Copy(bs []byte) error {
+	format, _ := c.wm.Option("clipboardformat").(string)
+	cmd, _ := c.wm.Option("clipboardcopy").(string)
+	bs = clipboard.Encode(bs, format)
+	tick := atomic.AddUint64(&c.tick, 1)
+	go func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if atomic.LoadUint64(&c.tick) != tick {
+			return
+		}
+		if cmd != "" {
+			c.report(clipboard.Copy(cmd, bs))
+		} else {
+			c.report(c.ui.SetClipboard(bs))
+		}
+	}()
+	return nil
+}
+
+// Paste returns the contents of the clipboard, which are decoded from the
+// hexadecimal string in the hex format of the clipboardformat option.
+func (c *systemClipboard) Paste() ([]byte, error) {
+	cmd, _ := c.wm.Option("clipboardpaste").(string)
+	if cmd == "" {
+		// the terminal clipboard cannot be read
+		return nil, nil
+	}
+	c.mu.Lock()
+	bs, err := clipboard.Paste(cmd)
+	c.mu.Unlock()
+	if err != nil {
+		c.report(err)
+		return nil, err
+	}
+	if format, _ := c.wm.Option("clipboardformat").(string); format == "hex" {
+		return clipboard.Decode(bs), nil
+	}
+	return bs, nil
+}
+
+// report emits the error asynchronously, because the clipboard is accessed
+// while the window is handling an event.
+func (c *systemClipboard) report(err error) {
+	if err != nil {
+		go func() { c.eventCh <- event.Event{Type: event.Error, Error: err} }()
+	}
+}
@@ -0,0 +1,82 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/state"
+)
+
+// LoadConfig reads the commands in the configuration file, which are
+// executed on Run before the first redraw. Empty lines and the lines starting
+// with a double quote are ignored. The first invalid command is reported on
+// the first screen.
+func (e *Editor) LoadConfig(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for i := 1; s.Scan(); i++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, `"`) {
+			continue
+		}
+		ev, err := e.cmdline.Parse(line)
+		if err != nil {
+			if e.err == nil {
+				e.err = fmt.Errorf("%s:%d: %s", filepath.Base(name), i, err)
+				e.errtyp = state.MessageError
+			}
+			continue
+		}
+		if ev.CmdName != "" {
+			e.config = append(e.config, ev)
+		}
+	}
+	return s.Err()
+}
+
+// Command adds the command executed on Run after the configuration file,
+// which is used by the command line flags.
+func (e *Editor) Command(cmdline string) error {
+	ev, err := e.cmdline.Parse(cmdline)
+	if err != nil {
+		return err
+	}
+	if ev.CmdName != "" {
+		e.config = append(e.config, ev)
+	}
+	return nil
+}
+
+// source executes the commands of the configuration file. The events sent
+// back while executing are handled here since the editor is not listening yet.
+func (e *Editor) source() {
+	if len(e.config) == 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case ev := <-e.eventCh:
+				if ev.Type == event.Error || ev.Type == event.Info {
+					e.emit(ev)
+				}
+			case <-e.redrawCh:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for _, ev := range e.config {
+		e.emit(ev)
+	}
+	close(done)
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/key"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
 )
@@ -15,6 +17,17 @@ type Editor struct {
 	ui            UI
 	wm            Manager
 	cmdline       Cmdline
+	kms           map[mode.Mode]*key.Manager
+	feeding       []pendingKeys
+	mapDepth      int
+	recorder      *key.Recorder
+	recording     rune
+	lastMacro     rune
+	change        []event.Event
+	lastChange    []event.Event
+	operator      event.Type
+	register      rune
+	visualBlock   bool
 	mode          mode.Mode
 	prevMode      mode.Mode
 	searchTarget  string
@@ -25,9 +38,14 @@ type Editor struct {
 	eventCh       chan event.Event
 	redrawCh      chan struct{}
 	cmdlineCh     chan event.Event
+	config        []event.Event
 	mu            *sync.Mutex
 }
 
+// checkTimeInterval is the interval to check whether the files are changed
+// outside of the editor.
+var checkTimeInterval = time.Second
+
 // NewEditor creates a new editor.
 func NewEditor(ui UI, wm Manager, cmdline Cmdline) *Editor {
 	return &Editor{
@@ -46,18 +64,37 @@ func (e *Editor) Init() error {
 	e.cmdlineCh = make(chan event.Event)
 	e.cmdline.Init(e.eventCh, e.cmdlineCh, e.redrawCh)
 	e.wm.Init(e.eventCh, e.redrawCh)
+	e.wm.SetClipboard(&systemClipboard{ui: e.ui, wm: e.wm, eventCh: e.eventCh, mu: new(sync.Mutex)})
+	e.kms = defaultKeyManagers()
+	e.recorder = key.NewRecorder()
+	for _, km := range e.kms {
+		km.SetRecorder(e.recorder)
+	}
 	e.mu = new(sync.Mutex)
 	return nil
 }
 
 func (e *Editor) listen() {
 	go func() {
-		for {
-			<-e.redrawCh
+		for range e.redrawCh {
 			e.redraw()
 		}
 	}()
-	for ev := range e.eventCh {
+	// the files are checked in this goroutine, since the window manager
+	// responds to the event by sending to the buffered event channel
+	ticker := time.NewTicker(checkTimeInterval)
+	defer ticker.Stop()
+	for {
+		var ev event.Event
+		var ok bool
+		select {
+		case ev, ok = <-e.eventCh:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			ev = event.Event{Type: event.CheckTime}
+		}
 		if redraw, finish := e.emit(ev); redraw {
 			e.redrawCh <- struct{}{}
 		} else if finish {
@@ -68,7 +105,7 @@ func (e *Editor) listen() {
 
 func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 	e.mu.Lock()
-	if ev.Type != event.Redraw {
+	if ev.Type != event.Redraw && ev.Type != event.CheckTime {
 		e.prevEventType = ev.Type
 	}
 	switch ev.Type {
@@ -88,11 +125,73 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 				e.mu.Lock()
 				e.err, e.errtyp = err, state.MessageError
 				e.mu.Unlock()
+				redraw = true
+				return
 			}
+			// the files may be changed while the editor is suspended
+			e.wm.Emit(event.Event{Type: event.CheckTime})
 			redraw = true
 			return
 		}
 		redraw = true
+	case event.Map, event.Unmap:
+		var err error
+		if ev.Type == event.Map {
+			err = e.mapKeys(ev)
+		} else {
+			err = e.unmapKeys(ev)
+		}
+		if err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		}
+		redraw = true
+	case event.ExecuteKeys, event.ExecuteKeysNoremap:
+		if err := e.executeKeys(ev); err != nil {
+			e.err, e.errtyp = err, state.MessageError
+			redraw = true
+		}
+	case event.RecordMacro, event.StopRecordMacro, event.ExecuteMacro:
+		var err error
+		switch ev.Type {
+		case event.RecordMacro:
+			err = e.recordMacro(ev)
+		case event.StopRecordMacro:
+			err = e.stopRecordMacro()
+		default:
+			err = e.executeMacro(ev)
+		}
+		if err != nil {
+			e.err, e.errtyp = err, state.MessageError
+		}
+		redraw = true
+	case event.SelectRegister:
+		e.register = ev.Rune
+	case event.Repeat:
+		events := e.repeatChange(ev.Count)
+		e.mu.Unlock()
+		for _, ev := range events {
+			e.emit(ev)
+		}
+		return
+	case event.FeedKeys:
+		ev = e.feedKeys()
+		e.mu.Unlock()
+		if ev.Type != event.Nop {
+			redraw, finish = e.emit(ev)
+		}
+		// the next keys are fed after the event is emitted, so that the
+		// event channel is available to the window manager
+		e.mu.Lock()
+		if len(e.feeding) > 0 && !finish {
+			go func() { e.eventCh <- event.Event{Type: event.FeedKeys} }()
+		}
+		e.mu.Unlock()
+		return
+	case event.CheckTime:
+		// the files are checked in any mode, and the command line is kept
+		e.mu.Unlock()
+		e.wm.Emit(ev)
+		return
 	case event.Info:
 		e.err, e.errtyp = ev.Error, state.MessageInfo
 		redraw = true
@@ -104,6 +203,17 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 		e.wm.Resize(width, height-1)
 		redraw = true
 	default:
+		pending := e.mode == mode.OperatorPending
+		visual := e.mode == mode.Visual && event.IsVisualOperation(ev.Type) &&
+			!(e.visualBlock && (ev.Type == event.OperatorChange || ev.Type == event.Put))
+		if pending && event.IsOperator(ev.Type) && ev.Type != e.operator {
+			ev.Type = event.CancelOperator
+		}
+		if event.IsOperator(ev.Type) || ev.Type == event.Put || ev.Type == event.PutBefore {
+			ev.Rune = e.register
+		}
+		e.register = 0
+		e.recordChange(ev)
 		switch ev.Type {
 		case event.StartInsert, event.StartInsertHead, event.StartAppend, event.StartAppendEnd:
 			e.mode, e.prevMode = mode.Insert, e.mode
@@ -111,10 +221,17 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 			e.mode, e.prevMode = mode.Replace, e.mode
 		case event.ExitInsert:
 			e.mode, e.prevMode = mode.Normal, e.mode
-		case event.StartVisual:
+		case event.StartVisual, event.StartVisualBlock:
+			e.mode, e.prevMode = mode.Visual, e.mode
+			e.visualBlock = ev.Type == event.StartVisualBlock
+		case event.ReselectVisual:
 			e.mode, e.prevMode = mode.Visual, e.mode
 		case event.ExitVisual:
 			e.mode, e.prevMode = mode.Normal, e.mode
+		case event.OperatorDelete, event.OperatorYank, event.OperatorChange, event.OperatorSwitchCase:
+			if e.mode == mode.Normal {
+				e.mode, e.prevMode, e.operator = mode.OperatorPending, e.mode, ev.Type
+			}
 		case event.StartCmdlineCommand:
 			if e.mode == mode.Visual {
 				ev.Arg = "'<,'>"
@@ -134,7 +251,11 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 		case event.ExitCmdline:
 			e.mode, e.prevMode = mode.Normal, e.mode
 		case event.ExecuteCmdline:
-			e.mode, e.prevMode = mode.Normal, e.mode
+			if e.mode == mode.Search && e.prevMode == mode.OperatorPending {
+				e.mode, e.prevMode = mode.OperatorPending, e.mode
+			} else {
+				e.mode, e.prevMode = mode.Normal, e.mode
+			}
 		case event.ExecuteSearch:
 			e.searchTarget, e.searchMode = ev.Arg, ev.Rune
 		case event.NextSearch:
@@ -142,6 +263,15 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 		case event.PreviousSearch:
 			ev.Arg, ev.Rune = e.searchTarget, e.searchMode
 		}
+		if pending && e.mode == mode.OperatorPending {
+			// the change operator starts insert mode after the motion succeeds
+			e.mode, e.prevMode = mode.Normal, e.mode
+		} else if visual {
+			e.mode, e.prevMode = mode.Normal, e.mode
+			if ev.Type == event.OperatorChange {
+				e.mode = mode.Insert
+			}
+		}
 		if e.mode == mode.Cmdline || e.mode == mode.Search ||
 			ev.Type == event.ExitCmdline || ev.Type == event.ExecuteCmdline {
 			e.mu.Unlock()
@@ -151,10 +281,33 @@ func (e *Editor) emit(ev event.Event) (redraw bool, finish bool) {
 				e.prevMode, e.err = e.mode, nil
 			}
 			ev.Mode = e.mode
+			if pending {
+				ev.Mode = mode.OperatorPending
+			} else if visual {
+				ev.Mode = mode.Visual
+			} else if e.mode == mode.OperatorPending {
+				ev.Mode = e.prevMode
+			}
 			width, height := e.ui.Size()
 			e.wm.Resize(width, height-1)
 			e.mu.Unlock()
+			if ev.Mode == mode.OperatorPending {
+				if e.wm.Operate(ev) == mode.Insert {
+					e.mu.Lock()
+					e.mode = mode.Insert
+					e.mu.Unlock()
+					redraw = true
+				} else if e.operator == event.OperatorChange {
+					e.mu.Lock()
+					e.change = nil
+					e.mu.Unlock()
+				}
+				return
+			}
 			e.wm.Emit(ev)
+			if ev.Type == event.Set || ev.Type == event.SetLocal {
+				e.setTimeout()
+			}
 		}
 		return
 	}
@@ -167,6 +320,11 @@ func (e *Editor) Open(filename string) (err error) {
 	return e.wm.Open(filename)
 }
 
+// Recover opens the file, and recovers the changes from the swap file.
+func (e *Editor) Recover(filename string) (err error) {
+	return e.wm.Recover(filename)
+}
+
 // OpenEmpty creates a new window.
 func (e *Editor) OpenEmpty() (err error) {
 	return e.wm.Open("")
@@ -177,10 +335,11 @@ func (e *Editor) Run() error {
 	if err := e.ui.Init(e.eventCh); err != nil {
 		return err
 	}
+	e.source()
 	if err := e.redraw(); err != nil {
 		return err
 	}
-	go e.ui.Run(defaultKeyManagers())
+	go e.ui.Run(e.kms)
 	go e.cmdline.Run()
 	e.listen()
 	return nil
@@ -200,6 +359,7 @@ func (e *Editor) redraw() (err error) {
 	}
 	s.WindowStates[windowIndex].Mode = e.mode
 	s.Mode, s.PrevMode, s.Error, s.ErrorType = e.mode, e.prevMode, e.err, e.errtyp
+	s.Recording = e.recording
 	if s.Mode != mode.Visual && s.PrevMode != mode.Visual {
 		for _, ws := range s.WindowStates {
 			ws.VisualStart = -1
@@ -226,9 +386,10 @@ func (e *Editor) suspend() error {
 
 // Close terminates the editor.
 func (e *Editor) Close() error {
+	// the windows are closed first since they may send to the channels
+	e.wm.Close()
 	close(e.eventCh)
 	close(e.redrawCh)
 	close(e.cmdlineCh)
-	e.wm.Close()
 	return e.ui.Close()
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,8 +20,10 @@ import (
 )
 
 type testUI struct {
-	eventCh chan<- event.Event
-	mu      *sync.Mutex
+	eventCh   chan<- event.Event
+	mu        *sync.Mutex
+	clipboard []byte
+	cmu       sync.Mutex
 }
 
 func newTestUI() *testUI {
@@ -42,6 +45,13 @@ func (ui *testUI) Size() (int, int) { return 10, 10 }
 
 func (ui *testUI) Redraw(_ state.State) error { return nil }
 
+func (ui *testUI) SetClipboard(bs []byte) error {
+	ui.cmu.Lock()
+	defer ui.cmu.Unlock()
+	ui.clipboard = bs
+	return nil
+}
+
 func (ui *testUI) Close() error { return nil }
 
 func (ui *testUI) Emit(e event.Event) {
@@ -240,3 +250,568 @@ func TestEditorCmdlineQuit(t *testing.T) {
 		t.Errorf("err should be nil but got: %v", err)
 	}
 }
+
+func TestEditorLoadConfig(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bedrc")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("\" comment\nset columns=7\n\n:set display=decimal\nfoo\nset group=3\n"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.LoadConfig(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := filepath.Base(f.Name()) + ":5: unknown command: foo"; editor.err == nil || editor.err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, editor.err)
+	}
+	if err := editor.LoadConfig(f.Name() + "-none"); err == nil {
+		t.Errorf("err should not be nil")
+	}
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "invalid value for group: 3"; editor.err == nil || editor.err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, editor.err)
+	}
+	windowStates, _, _, _ := editor.wm.State()
+	if windowStates[0].Width != 7 || windowStates[0].Display != "decimal" {
+		t.Errorf("width and display should be 7 and decimal but got %d and %s",
+			windowStates[0].Width, windowStates[0].Display)
+	}
+}
+
+func TestEditorMapping(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-mapping")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]", Arg: "<Leader>a <C-a>"})
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]", Arg: "<Leader>i <Leader>a<Leader>a"})
+		ui.Emit(event.Event{Type: event.Map, CmdName: "nm[ap]", Arg: "<Leader>w :w " + f.Name() + "<CR>"})
+		ui.Emit(event.Event{Type: event.ExecuteKeys, Arg: "<Leader>i<Leader>i"})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.ExecuteKeysNoremap, Arg: "<C-a>", Count: 3})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.ExecuteKeys, Arg: "<Leader>w"})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Unmap, CmdName: "nun[map]", Arg: "<Leader>z"})
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "no such mapping: <Leader>z"; editor.err == nil || editor.err.Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, editor.err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "\x07" {
+		t.Errorf("file contents should be %q but got %q", "\x07", string(bs))
+	}
+}
+
+func TestEditorMacro(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-macro")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	km := editor.kms[mode.Normal]
+	go func() {
+		for _, k := range []key.Key{"q", "a", "c-a"} {
+			if e := km.Press(k); e.Type != event.Nop {
+				ui.Emit(e)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		// q stops recording without waiting for the following key
+		if e := km.Press("q"); e.Type != event.StopRecordMacro {
+			t.Errorf("q should stop recording but got: %v", e.Type)
+		}
+		ui.Emit(event.Event{Type: event.StopRecordMacro})
+		time.Sleep(100 * time.Millisecond)
+		if e := km.Press("q"); e.Type != event.Nop {
+			t.Errorf("q should wait for the register but got: %v", e.Type)
+		}
+		ui.Emit(km.Flush())
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.ExecuteMacro, Rune: 'a', Count: 3})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.ExecuteMacro, Rune: '@'})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if bs, err := editor.wm.Register('a'); err != nil || string(bs) != "<C-a>" {
+		t.Errorf("register a should be %q but got: %q, %v", "<C-a>", string(bs), err)
+	}
+	if editor.recording != 0 {
+		t.Errorf("recording should be stopped but got: %c", editor.recording)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(bs) != "\x05" {
+		t.Errorf("file contents should be %q but got %q", "\x05", string(bs))
+	}
+}
+
+func TestEditorRepeat(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-repeat")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		for _, e := range []event.Event{
+			{Type: event.Increment, Count: 2},
+			{Type: event.Repeat},
+			{Type: event.Repeat, Count: 3},
+			{Type: event.StartAppend},
+			{Type: event.Rune, Rune: '0'},
+			{Type: event.Rune, Rune: 'a'},
+			{Type: event.ExitInsert},
+			{Type: event.Repeat, Count: 2},
+			{Type: event.StartAppendEnd},
+		} {
+			ui.Emit(e)
+		}
+		for _, r := range "000102030405060708090a0b" {
+			ui.Emit(event.Event{Type: event.Rune, Rune: r})
+		}
+		ui.Emit(event.Event{Type: event.ExitInsert})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Set, Arg: "columns=16"})
+		time.Sleep(100 * time.Millisecond)
+		for _, e := range []event.Event{
+			{Type: event.PageTop},
+			{Type: event.CursorHead},
+			{Type: event.DeleteByte, Count: 5},
+			{Type: event.Repeat},
+			{Type: event.Repeat, Count: 3},
+			{Type: event.StartReplaceByte},
+			{Type: event.Rune, Rune: '4'},
+			{Type: event.Rune, Rune: '1'},
+			{Type: event.ExitInsert},
+			{Type: event.CursorNext},
+			{Type: event.Repeat},
+			{Type: event.CursorNext},
+			{Type: event.Repeat, Count: 3},
+		} {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "AAA"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorOperator(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-operator")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		events := []event.Event{{Type: event.StartInsert}}
+		for _, r := range "414243444546" {
+			events = append(events, event.Event{Type: event.Rune, Rune: r})
+		}
+		for _, e := range append(events, []event.Event{
+			{Type: event.ExitInsert},
+			{Type: event.PageTop},
+			{Type: event.OperatorDelete},
+			{Type: event.CursorNext, Count: 2},
+			{Type: event.Repeat},
+			{Type: event.OperatorSwitchCase},
+			{Type: event.CursorNext, Count: 2},
+			{Type: event.OperatorYank},
+			{Type: event.CancelOperator},
+			{Type: event.OperatorDelete},
+			{Type: event.OperatorYank},
+			{Type: event.OperatorChange},
+			{Type: event.CursorNext},
+			{Type: event.Rune, Rune: '3'},
+			{Type: event.Rune, Rune: '0'},
+			{Type: event.ExitInsert},
+		}...) {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.mode != mode.Normal {
+		t.Errorf("mode should be %d but got %d", mode.Normal, editor.mode)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "0f"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorOperatorFailedMotion(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-operator-failed-motion")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		events := []event.Event{{Type: event.StartInsert}}
+		for _, r := range "41424344" {
+			events = append(events, event.Event{Type: event.Rune, Rune: r})
+		}
+		for _, e := range append(events, []event.Event{
+			{Type: event.ExitInsert},
+			{Type: event.CursorHead},
+			{Type: event.OperatorChange},
+			{Type: event.FindByte, Arg: "45"},
+			{Type: event.Rune, Rune: '3'},
+			{Type: event.Rune, Rune: '0'},
+			{Type: event.OperatorChange},
+			{Type: event.CursorLeft},
+			{Type: event.Rune, Rune: '3'},
+			{Type: event.Rune, Rune: '0'},
+			{Type: event.OperatorChange},
+			{Type: event.FindByte, Arg: "42"},
+			{Type: event.Rune, Rune: '3'},
+			{Type: event.Rune, Rune: '0'},
+			{Type: event.ExitInsert},
+		}...) {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.mode != mode.Normal {
+		t.Errorf("mode should be %d but got %d", mode.Normal, editor.mode)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "0CD"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorVisual(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-visual")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		events := []event.Event{{Type: event.StartInsert}}
+		for _, r := range "414243444546" {
+			events = append(events, event.Event{Type: event.Rune, Rune: r})
+		}
+		for _, e := range append(events, []event.Event{
+			{Type: event.ExitInsert},
+			{Type: event.PageTop},
+			{Type: event.StartVisual},
+			{Type: event.CursorNext, Count: 2},
+			{Type: event.OperatorDelete},
+			{Type: event.StartVisual},
+			{Type: event.CursorNext},
+			{Type: event.ReplaceSelection, Arg: "30"},
+			{Type: event.ReselectVisual},
+			{Type: event.OperatorChange},
+			{Type: event.Rune, Rune: '4'},
+			{Type: event.Rune, Rune: '1'},
+			{Type: event.ExitInsert},
+		}...) {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if editor.mode != mode.Normal {
+		t.Errorf("mode should be %d but got %d", mode.Normal, editor.mode)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	bs, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "AF"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorClipboard(t *testing.T) {
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	f, err := ioutil.TempFile("", "bed-test-editor-clipboard")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	g, err := ioutil.TempFile("", "bed-test-editor-clipboard-copy")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(g.Name())
+	if err := editor.OpenEmpty(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	go func() {
+		events := []event.Event{{Type: event.StartInsert}}
+		for _, r := range "414243" {
+			events = append(events, event.Event{Type: event.Rune, Rune: r})
+		}
+		for _, e := range append(events, []event.Event{
+			{Type: event.ExitInsert},
+			{Type: event.PageTop},
+			{Type: event.StartVisual},
+			{Type: event.CursorNext},
+			{Type: event.SelectRegister, Rune: '+'},
+			{Type: event.OperatorYank},
+		}...) {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Set, Arg: `clipboardformat=raw clipboardcopy=cat\ >\ ` + g.Name() +
+			` clipboardpaste=printf\ 5a5a`})
+		time.Sleep(100 * time.Millisecond)
+		for _, e := range []event.Event{
+			{Type: event.SelectRegister, Rune: '+'},
+			{Type: event.OperatorYank},
+			{Type: event.CursorNext, Count: 2},
+			{Type: event.CursorNext, Count: 2},
+			{Type: event.SelectRegister, Rune: '*'},
+			{Type: event.Put},
+		} {
+			ui.Emit(e)
+		}
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Write, Arg: f.Name()})
+		time.Sleep(100 * time.Millisecond)
+		ui.Emit(event.Event{Type: event.Quit})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	ui.cmu.Lock()
+	if expected := "4142"; string(ui.clipboard) != expected {
+		t.Errorf("clipboard should be %q but got %q", expected, string(ui.clipboard))
+	}
+	ui.cmu.Unlock()
+	bs, err := ioutil.ReadFile(g.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "AB"; string(bs) != expected {
+		t.Errorf("clipboard should be %q but got %q", expected, string(bs))
+	}
+	bs, err = ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if expected := "ABC5a5a"; string(bs) != expected {
+		t.Errorf("file contents should be %q but got %q", expected, string(bs))
+	}
+}
+
+func TestEditorCheckTime(t *testing.T) {
+	defer func(interval time.Duration) { checkTimeInterval = interval }(checkTimeInterval)
+	checkTimeInterval = 10 * time.Millisecond
+	f, err := ioutil.TempFile("", "bed-test-editor-check-time")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("abc"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	ui := newTestUI()
+	editor := NewEditor(ui, window.NewManager(), cmdline.NewCmdline())
+	if err := editor.Init(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	var errs []error
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := ioutil.WriteFile(f.Name(), []byte("abcdef"), 0644); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		editor.mu.Lock()
+		errs = append(errs, editor.err)
+		editor.err = nil
+		editor.mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		editor.mu.Lock()
+		errs = append(errs, editor.err)
+		editor.mu.Unlock()
+		ui.Emit(event.Event{Type: event.Quit, Bang: true})
+	}()
+	if err := editor.Run(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := editor.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	expected := filepath.Base(f.Name()) + ": file changed since reading it (use :e! to reload)"
+	if len(errs) != 2 || errs[0] == nil || errs[0].Error() != expected {
+		t.Errorf("err should be %q but got: %v", expected, errs)
+	}
+	if len(errs) == 2 && errs[1] != nil {
+		t.Errorf("the change should be warned only once but got: %v", errs[1])
+	}
+}
@@ -48,6 +48,15 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.Increment, "+")
 	km.Register(event.Decrement, "c-x")
 	km.Register(event.Decrement, "-")
+	km.Register(event.ToggleBit, "~")
+	km.Register(event.OperatorDelete, "d")
+	km.Register(event.OperatorYank, "y")
+	km.Register(event.OperatorChange, "c")
+	km.Register(event.OperatorSwitchCase, "g", "~")
+	registerFindByte(km)
+	registerSelectRegister(km)
+	km.Register(event.Put, "p")
+	km.Register(event.PutBefore, "P")
 
 	km.Register(event.StartInsert, "i")
 	km.Register(event.StartInsertHead, "I")
@@ -58,8 +67,11 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 
 	km.Register(event.Undo, "u")
 	km.Register(event.Redo, "c-r")
+	km.Register(event.Repeat, ".")
 
 	km.Register(event.StartVisual, "v")
+	km.Register(event.StartVisualBlock, "c-v")
+	km.Register(event.ReselectVisual, "g", "v")
 
 	km.Register(event.SwitchFocus, "tab")
 	km.Register(event.SwitchFocus, "backtab")
@@ -68,6 +80,18 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.StartCmdlineSearchBackward, "?")
 	km.Register(event.NextSearch, "n")
 	km.Register(event.PreviousSearch, "N")
+	for c := 'a'; c <= 'z'; c++ {
+		km.Register(event.SetMark, "m", key.Key(c))
+		km.Register(event.JumpMark, "'", key.Key(c))
+		km.Register(event.JumpMark, "`", key.Key(c))
+	}
+	for _, c := range "\"0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		km.Register(event.RecordMacro, "q", key.Key(c))
+		km.Register(event.ExecuteMacro, "@", key.Key(c))
+	}
+	km.Register(event.ExecuteMacro, "@", "@")
+	km.Register(event.NextAnnotation, "]", "a")
+	km.Register(event.PreviousAnnotation, "[", "a")
 
 	km.Register(event.New, "c-w", "n")
 	km.Register(event.New, "c-w", "c-n")
@@ -124,7 +148,25 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.ExitVisual, "c-c")
 	km.Register(event.SwitchVisualEnd, "o")
 	km.Register(event.SwitchVisualEnd, "O")
+	km.Register(event.StartVisualBlock, "c-v")
 	km.Register(event.StartCmdlineCommand, ":")
+	km.Register(event.OperatorDelete, "d")
+	km.Register(event.OperatorDelete, "x")
+	km.Register(event.OperatorDelete, "delete")
+	km.Register(event.OperatorYank, "y")
+	km.Register(event.OperatorChange, "c")
+	km.Register(event.OperatorChange, "s")
+	km.Register(event.OperatorSwitchCase, "~")
+	km.Register(event.Increment, "c-a")
+	km.Register(event.Decrement, "c-x")
+	km.Register(event.Put, "p")
+	km.Register(event.Put, "P")
+	registerSelectRegister(km)
+	for _, h := range hexDigits {
+		for _, l := range hexDigits {
+			km.RegisterArg(event.ReplaceSelection, string([]rune{h, l}), "r", key.Key(h), key.Key(l))
+		}
+	}
 
 	km.Register(event.CursorUp, "up")
 	km.Register(event.CursorDown, "down")
@@ -151,10 +193,48 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	km.Register(event.PageDownHalf, "c-d")
 	km.Register(event.PageTop, "g", "g")
 	km.Register(event.PageEnd, "G")
+	registerFindByte(km)
 	km.Register(event.SwitchFocus, "tab")
 	km.Register(event.SwitchFocus, "backtab")
 	kms[mode.Visual] = km
 
+	km = key.NewManager(true)
+	km.Register(event.CancelOperator, "escape")
+	km.Register(event.CancelOperator, "c-c")
+	km.Register(event.OperatorDelete, "d")
+	km.Register(event.OperatorYank, "y")
+	km.Register(event.OperatorChange, "c")
+	km.Register(event.OperatorSwitchCase, "~")
+	km.Register(event.OperatorSwitchCase, "g", "~")
+
+	km.Register(event.CursorUp, "up")
+	km.Register(event.CursorDown, "down")
+	km.Register(event.CursorLeft, "left")
+	km.Register(event.CursorRight, "right")
+	km.Register(event.PageTop, "home")
+	km.Register(event.PageEnd, "end")
+	km.Register(event.CursorUp, "k")
+	km.Register(event.CursorDown, "j")
+	km.Register(event.CursorLeft, "h")
+	km.Register(event.CursorRight, "l")
+	km.Register(event.CursorPrev, "b")
+	km.Register(event.CursorNext, "w")
+	km.Register(event.CursorHead, "0")
+	km.Register(event.CursorHead, "^")
+	km.Register(event.CursorEnd, "$")
+	km.Register(event.PageTop, "g", "g")
+	km.Register(event.PageEnd, "G")
+	registerFindByte(km)
+	km.Register(event.StartCmdlineSearchForward, "/")
+	km.Register(event.StartCmdlineSearchBackward, "?")
+	km.Register(event.NextSearch, "n")
+	km.Register(event.PreviousSearch, "N")
+	for c := 'a'; c <= 'z'; c++ {
+		km.Register(event.JumpMark, "'", key.Key(c))
+		km.Register(event.JumpMark, "`", key.Key(c))
+	}
+	kms[mode.OperatorPending] = km
+
 	km = key.NewManager(false)
 	km.Register(event.CursorLeft, "left")
 	km.Register(event.CursorLeft, "c-b")
@@ -182,3 +262,25 @@ func defaultKeyManagers() map[mode.Mode]*key.Manager {
 	kms[mode.Search] = km
 	return kms
 }
+
+const hexDigits = "0123456789abcdef"
+
+// registerSelectRegister registers "{reg} to specify the register for the
+// next yank, delete or put.
+func registerSelectRegister(km *key.Manager) {
+	for _, c := range "\"0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ+*" {
+		km.Register(event.SelectRegister, "\"", key.Key(c))
+	}
+}
+
+// registerFindByte registers f{byte} and F{byte} with the byte in two
+// hexadecimal digits.
+func registerFindByte(km *key.Manager) {
+	for _, h := range hexDigits {
+		for _, l := range hexDigits {
+			arg := string([]rune{h, l})
+			km.RegisterArg(event.FindByte, arg, "f", key.Key(h), key.Key(l))
+			km.RegisterArg(event.FindByteBackward, arg, "F", key.Key(h), key.Key(l))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/key"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/register"
+)
+
+// recordMacro starts recording the keys to the register. The key q stops
+// recording without waiting for the register key while recording, and the
+// keys q{reg} from the mappings stop recording and execute the register key.
+func (e *Editor) recordMacro(ev event.Event) error {
+	if e.recording != 0 {
+		if err := e.stopRecordMacro(); err != nil {
+			return err
+		}
+		return e.feed([]key.Key{key.Key(ev.Rune)}, true)
+	}
+	if !register.Valid(ev.Rune) {
+		return fmt.Errorf("invalid register: %c", ev.Rune)
+	}
+	e.recorder.Start()
+	e.recording, e.err = ev.Rune, nil
+	e.kms[mode.Normal].Override(event.StopRecordMacro, "q")
+	return nil
+}
+
+// stopRecordMacro stops recording and stores the keys to the register in the
+// key notation. The keys from the last q, which stopped recording, are
+// excluded.
+func (e *Editor) stopRecordMacro() error {
+	if e.recording == 0 {
+		return nil
+	}
+	keys := e.recorder.Stop()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i] == "q" {
+			keys = keys[:i]
+			break
+		}
+	}
+	name := e.recording
+	e.recording = 0
+	e.kms[mode.Normal].RemoveOverride("q")
+	return e.wm.SetRegister(name, []byte(key.FormatKeys(keys)))
+}
+
+// executeMacro executes the keys in the register count times. The register
+// @ means the last executed register.
+func (e *Editor) executeMacro(ev event.Event) error {
+	name := ev.Rune
+	if name == '@' {
+		if name = e.lastMacro; name == 0 {
+			return errors.New("no previously used register")
+		}
+	}
+	bs, err := e.wm.Register(name)
+	if err != nil {
+		return err
+	}
+	e.lastMacro = name
+	if len(bs) == 0 {
+		return nil
+	}
+	keys, err := e.parseKeys(string(bs))
+	if err != nil {
+		return err
+	}
+	var macro []key.Key
+	for i := int64(0); i < ev.Count || i == 0; i++ {
+		macro = append(macro, keys...)
+	}
+	return e.feed(macro, true)
+}
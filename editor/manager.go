@@ -3,6 +3,8 @@ package editor
 import (
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/register"
 	"github.com/itchyny/bed/state"
 )
 
@@ -10,9 +12,15 @@ import (
 type Manager interface {
 	Init(chan<- event.Event, chan<- struct{})
 	Open(string) error
+	Recover(string) error
 	SetSize(int, int)
 	Resize(int, int)
 	Emit(event.Event)
+	Operate(event.Event) mode.Mode
+	Option(string) interface{}
+	Register(rune) ([]byte, error)
+	SetRegister(rune, []byte) error
+	SetClipboard(register.Clipboard)
 	State() (map[int]*state.WindowState, layout.Layout, int, error)
 	Close()
 }
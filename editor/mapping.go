@@ -0,0 +1,199 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/key"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
+)
+
+type mapCommand struct {
+	modes []mode.Mode
+	remap bool
+}
+
+var mapCommands = map[string]mapCommand{
+	"map":        {[]mode.Mode{mode.Normal, mode.Visual}, true},
+	"nm[ap]":     {[]mode.Mode{mode.Normal}, true},
+	"vm[ap]":     {[]mode.Mode{mode.Visual}, true},
+	"im[ap]":     {[]mode.Mode{mode.Insert}, true},
+	"no[remap]":  {[]mode.Mode{mode.Normal, mode.Visual}, false},
+	"nn[oremap]": {[]mode.Mode{mode.Normal}, false},
+	"vn[oremap]": {[]mode.Mode{mode.Visual}, false},
+	"ino[remap]": {[]mode.Mode{mode.Insert}, false},
+	"unm[ap]":    {[]mode.Mode{mode.Normal, mode.Visual}, false},
+	"nun[map]":   {[]mode.Mode{mode.Normal}, false},
+	"vu[nmap]":   {[]mode.Mode{mode.Visual}, false},
+	"iu[nmap]":   {[]mode.Mode{mode.Insert}, false},
+}
+
+var modeNames = map[mode.Mode]string{
+	mode.Normal: "n",
+	mode.Visual: "v",
+	mode.Insert: "i",
+}
+
+// maxMapDepth is the limit of the nested mappings on executing the keys.
+const maxMapDepth = 1000
+
+type pendingKeys struct {
+	keys  []key.Key
+	remap bool
+}
+
+// mapKeys adds the key mapping, or lists the mappings if the right hand side
+// is omitted.
+func (e *Editor) mapKeys(ev event.Event) error {
+	cmd := mapCommands[ev.CmdName]
+	lhs, rhs := ev.Arg, ""
+	if i := strings.IndexAny(lhs, " \t"); i >= 0 {
+		lhs, rhs = lhs[:i], strings.TrimSpace(lhs[i:])
+	}
+	keys, err := e.parseKeys(lhs)
+	if err != nil && lhs != "" {
+		return err
+	}
+	if rhs == "" {
+		var mappings []string
+		for _, m := range cmd.modes {
+			for _, kv := range e.kms[m].Mappings(keys) {
+				mappings = append(mappings, modeNames[m]+" "+kv[0]+" "+kv[1])
+			}
+		}
+		if len(mappings) == 0 {
+			return errors.New("no mappings found")
+		}
+		e.err, e.errtyp = errors.New(strings.Join(mappings, "  ")), state.MessageInfo
+		return nil
+	}
+	for _, m := range cmd.modes {
+		e.kms[m].Map(keys, rhs, cmd.remap)
+	}
+	return nil
+}
+
+// unmapKeys removes the key mapping.
+func (e *Editor) unmapKeys(ev event.Event) error {
+	if ev.Arg == "" {
+		return fmt.Errorf("an argument is required for %s", ev.CmdName)
+	}
+	keys, err := e.parseKeys(ev.Arg)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for _, m := range mapCommands[ev.CmdName].modes {
+		if e.kms[m].Unmap(keys) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no such mapping: %s", ev.Arg)
+	}
+	return nil
+}
+
+func (e *Editor) parseKeys(s string) ([]key.Key, error) {
+	leader, _ := e.wm.Option("mapleader").(string)
+	return key.ParseKeys(s, leader)
+}
+
+// setTimeout applies the timeoutlen option to the key managers.
+func (e *Editor) setTimeout() {
+	timeoutlen, _ := e.wm.Option("timeoutlen").(int64)
+	for _, km := range e.kms {
+		km.SetTimeout(time.Duration(timeoutlen) * time.Millisecond)
+	}
+}
+
+// executeKeys queues the keys of the mapping, which are fed one event at a
+// time by FeedKeys so that the editor keeps handling the events sent back
+// while executing the keys.
+func (e *Editor) executeKeys(ev event.Event) error {
+	keys, err := e.parseKeys(ev.Arg)
+	if err != nil {
+		return err
+	}
+	if ev.Count > 0 {
+		var count []key.Key
+		for _, c := range strconv.FormatInt(ev.Count, 10) {
+			count = append(count, key.Key(c))
+		}
+		keys = append(count, keys...)
+	}
+	return e.feed(keys, ev.Type == event.ExecuteKeys)
+}
+
+// feed queues the keys to be executed.
+func (e *Editor) feed(keys []key.Key, remap bool) error {
+	if e.mapDepth++; e.mapDepth > maxMapDepth {
+		e.feeding, e.mapDepth = nil, 0
+		return errors.New("recursive mapping")
+	}
+	feeding := len(e.feeding) > 0
+	e.feeding = append([]pendingKeys{{keys, remap}}, e.feeding...)
+	if !feeding {
+		go func() { e.eventCh <- event.Event{Type: event.FeedKeys} }()
+	}
+	return nil
+}
+
+// feedKeys returns the event of the keys at the head of the queue.
+func (e *Editor) feedKeys() event.Event {
+	for len(e.feeding) > 0 && len(e.feeding[0].keys) == 0 {
+		e.feeding = e.feeding[1:]
+	}
+	if len(e.feeding) == 0 {
+		e.mapDepth = 0
+		return event.Event{Type: event.Nop}
+	}
+	p := &e.feeding[0]
+	ev, n := e.kms[e.mode].Match(p.keys, p.remap)
+	if n == 0 {
+		ev, n = event.Event{Type: event.Nop}, 1
+		if r, size := utf8.DecodeRuneInString(string(p.keys[0])); size == len(p.keys[0]) {
+			ev = event.Event{Type: event.Rune, Rune: r}
+		}
+	}
+	p.keys = p.keys[n:]
+	if ev.Type == event.StartCmdlineCommand && (e.mode == mode.Normal || e.mode == mode.Visual) {
+		if cmd, ok := e.feedCmdline(p, ev); ok {
+			return cmd
+		}
+	}
+	return ev
+}
+
+// feedCmdline executes the command line up to <CR> in the mapping, without
+// going through the command line.
+func (e *Editor) feedCmdline(p *pendingKeys, ev event.Event) (event.Event, bool) {
+	var cmdline string
+	for i, k := range p.keys {
+		if k == "enter" {
+			if e.mode == mode.Visual {
+				cmdline = "'<,'>" + cmdline
+			} else if ev.Count > 0 {
+				cmdline = fmt.Sprintf(".,.+%d", ev.Count-1) + cmdline
+			}
+			p.keys = p.keys[i+1:]
+			e.mode, e.prevMode = mode.Normal, mode.Cmdline
+			cmd, err := e.cmdline.Parse(cmdline)
+			if err != nil {
+				return event.Event{Type: event.Error, Error: err}, true
+			}
+			return cmd, true
+		}
+		if utf8.RuneCountInString(string(k)) != 1 {
+			break
+		}
+		cmdline += string(k)
+	}
+	return event.Event{}, false
+}
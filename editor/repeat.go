@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mode"
+)
+
+// recordChange records the events of the last change for the dot command.
+// The change in insert or replace mode is recorded from the event to start
+// the mode until the event to exit the mode, and the change by the operator
+// is recorded with the motion. The changes in visual mode are not recorded,
+// since the selection cannot be restored at the cursor on repeating them.
+func (e *Editor) recordChange(ev event.Event) {
+	switch e.mode {
+	case mode.Normal:
+		switch ev.Type {
+		case event.DeleteByte, event.DeletePrevByte, event.Increment, event.Decrement,
+			event.ToggleBit, event.Put, event.PutBefore:
+			e.lastChange, e.change = []event.Event{ev}, nil
+		case event.StartInsert, event.StartInsertHead, event.StartAppend,
+			event.StartAppendEnd, event.StartReplaceByte, event.StartReplace:
+			e.change = []event.Event{ev}
+		case event.OperatorDelete, event.OperatorYank, event.OperatorChange, event.OperatorSwitchCase:
+			e.change = nil
+			if ev.Type != event.OperatorYank {
+				e.change = []event.Event{ev}
+			}
+		}
+	case mode.OperatorPending:
+		switch ev.Type {
+		case event.StartCmdlineSearchForward, event.StartCmdlineSearchBackward:
+			// the search is recorded on executing it
+		case event.CancelOperator:
+			e.change = nil
+		default:
+			if e.change != nil {
+				e.change = append(e.change, ev)
+				if e.change[0].Type != event.OperatorChange {
+					e.lastChange, e.change = e.change, nil
+				}
+			}
+		}
+	case mode.Insert, mode.Replace:
+		if e.change != nil {
+			e.change = append(e.change, ev)
+			if ev.Type == event.ExitInsert {
+				e.lastChange, e.change = e.change, nil
+			}
+		}
+	}
+}
+
+// repeatChange returns the events to repeat the last change. The count
+// replaces the count of the change, or repeats the change in insert mode.
+// The count of the operator replaces the counts of the operator and motion.
+func (e *Editor) repeatChange(count int64) []event.Event {
+	if len(e.lastChange) > 1 && event.IsOperator(e.lastChange[0].Type) && count > 0 {
+		events := append([]event.Event{}, e.lastChange...)
+		events[0].Count, events[1].Count = count, 0
+		return events
+	}
+	if len(e.lastChange) == 1 {
+		ev := e.lastChange[0]
+		if count > 0 {
+			ev.Count = count
+		}
+		return []event.Event{ev}
+	}
+	var events []event.Event
+	for i := int64(0); i < count || i == 0; i++ {
+		events = append(events, e.lastChange...)
+	}
+	return events
+}
@@ -9,6 +9,7 @@ type Event struct {
 	Count   int64
 	Rune    rune
 	CmdName string
+	Bang    bool
 	Arg     string
 	Error   error
 	Mode    mode.Mode
@@ -41,11 +42,14 @@ const (
 	PageEnd
 	JumpTo
 	JumpBack
+	FindByte
+	FindByteBackward
 
 	DeleteByte
 	DeletePrevByte
 	Increment
 	Decrement
+	ToggleBit
 	SwitchFocus
 
 	StartInsert
@@ -62,10 +66,23 @@ const (
 
 	Undo
 	Redo
+	Repeat
+
+	OperatorDelete
+	OperatorYank
+	OperatorChange
+	OperatorSwitchCase
+	CancelOperator
+	ReplaceSelection
+	Put
+	PutBefore
+	SelectRegister
 
 	StartVisual
+	StartVisualBlock
 	SwitchVisualEnd
 	ExitVisual
+	ReselectVisual
 
 	StartCmdlineCommand
 	StartCmdlineSearchForward
@@ -82,6 +99,32 @@ const (
 	ExecuteSearch
 	NextSearch
 	PreviousSearch
+	CountMatches
+	FindAll
+	NextMatch
+	PreviousMatch
+	ToggleInspector
+	ApplyTemplate
+	GotoField
+	ToggleFormat
+	GotoEntry
+	SetMark
+	JumpMark
+	Annotate
+	Unannotate
+	ToggleAnnotations
+	NextAnnotation
+	PreviousAnnotation
+	Set
+	SetLocal
+	Map
+	Unmap
+	ExecuteKeys
+	ExecuteKeysNoremap
+	FeedKeys
+	RecordMacro
+	StopRecordMacro
+	ExecuteMacro
 
 	Edit
 	New
@@ -103,6 +146,29 @@ const (
 	QuitAll
 	Write
 	WriteQuit
+	SaveAs
+	Update
+	WriteAll
+	WriteAllQuit
+	CheckTime
+	Filter
+	Read
 	Info
 	Error
 )
+
+// IsOperator reports whether the event type is an operator.
+func IsOperator(typ Type) bool {
+	return OperatorDelete <= typ && typ <= OperatorSwitchCase
+}
+
+// IsVisualOperation reports whether the event operates on the selection in
+// visual mode.
+func IsVisualOperation(typ Type) bool {
+	switch typ {
+	case ReplaceSelection, Increment, Decrement, Put:
+		return true
+	default:
+		return IsOperator(typ)
+	}
+}
@@ -0,0 +1,126 @@
+package event
+
+// names are the names of the event types, which can be bound to keys.
+var names = map[string]Type{
+	"CursorUp":                   CursorUp,
+	"CursorDown":                 CursorDown,
+	"CursorLeft":                 CursorLeft,
+	"CursorRight":                CursorRight,
+	"CursorPrev":                 CursorPrev,
+	"CursorNext":                 CursorNext,
+	"CursorHead":                 CursorHead,
+	"CursorEnd":                  CursorEnd,
+	"CursorGoto":                 CursorGoto,
+	"ScrollUp":                   ScrollUp,
+	"ScrollDown":                 ScrollDown,
+	"PageUp":                     PageUp,
+	"PageDown":                   PageDown,
+	"PageUpHalf":                 PageUpHalf,
+	"PageDownHalf":               PageDownHalf,
+	"PageTop":                    PageTop,
+	"PageEnd":                    PageEnd,
+	"JumpTo":                     JumpTo,
+	"JumpBack":                   JumpBack,
+	"FindByte":                   FindByte,
+	"FindByteBackward":           FindByteBackward,
+	"DeleteByte":                 DeleteByte,
+	"DeletePrevByte":             DeletePrevByte,
+	"Increment":                  Increment,
+	"Decrement":                  Decrement,
+	"SwitchFocus":                SwitchFocus,
+	"StartInsert":                StartInsert,
+	"StartInsertHead":            StartInsertHead,
+	"StartAppend":                StartAppend,
+	"StartAppendEnd":             StartAppendEnd,
+	"StartReplaceByte":           StartReplaceByte,
+	"StartReplace":               StartReplace,
+	"ExitInsert":                 ExitInsert,
+	"Backspace":                  Backspace,
+	"Delete":                     Delete,
+	"Undo":                       Undo,
+	"Redo":                       Redo,
+	"Repeat":                     Repeat,
+	"OperatorDelete":             OperatorDelete,
+	"OperatorYank":               OperatorYank,
+	"OperatorChange":             OperatorChange,
+	"OperatorSwitchCase":         OperatorSwitchCase,
+	"CancelOperator":             CancelOperator,
+	"ReplaceSelection":           ReplaceSelection,
+	"Put":                        Put,
+	"PutBefore":                  PutBefore,
+	"SelectRegister":             SelectRegister,
+	"StartVisual":                StartVisual,
+	"StartVisualBlock":           StartVisualBlock,
+	"SwitchVisualEnd":            SwitchVisualEnd,
+	"ExitVisual":                 ExitVisual,
+	"ReselectVisual":             ReselectVisual,
+	"StartCmdlineCommand":        StartCmdlineCommand,
+	"StartCmdlineSearchForward":  StartCmdlineSearchForward,
+	"StartCmdlineSearchBackward": StartCmdlineSearchBackward,
+	"BackspaceCmdline":           BackspaceCmdline,
+	"DeleteCmdline":              DeleteCmdline,
+	"DeleteWordCmdline":          DeleteWordCmdline,
+	"ClearToHeadCmdline":         ClearToHeadCmdline,
+	"ClearCmdline":               ClearCmdline,
+	"ExitCmdline":                ExitCmdline,
+	"CompleteForwardCmdline":     CompleteForwardCmdline,
+	"CompleteBackCmdline":        CompleteBackCmdline,
+	"ExecuteCmdline":             ExecuteCmdline,
+	"ExecuteSearch":              ExecuteSearch,
+	"NextSearch":                 NextSearch,
+	"PreviousSearch":             PreviousSearch,
+	"CountMatches":               CountMatches,
+	"FindAll":                    FindAll,
+	"NextMatch":                  NextMatch,
+	"PreviousMatch":              PreviousMatch,
+	"ToggleInspector":            ToggleInspector,
+	"ApplyTemplate":              ApplyTemplate,
+	"GotoField":                  GotoField,
+	"ToggleFormat":               ToggleFormat,
+	"GotoEntry":                  GotoEntry,
+	"SetMark":                    SetMark,
+	"JumpMark":                   JumpMark,
+	"Annotate":                   Annotate,
+	"Unannotate":                 Unannotate,
+	"ToggleAnnotations":          ToggleAnnotations,
+	"NextAnnotation":             NextAnnotation,
+	"PreviousAnnotation":         PreviousAnnotation,
+	"Set":                        Set,
+	"SetLocal":                   SetLocal,
+	"RecordMacro":                RecordMacro,
+	"StopRecordMacro":            StopRecordMacro,
+	"ExecuteMacro":               ExecuteMacro,
+	"Edit":                       Edit,
+	"New":                        New,
+	"Vnew":                       Vnew,
+	"Wincmd":                     Wincmd,
+	"FocusWindowUp":              FocusWindowUp,
+	"FocusWindowDown":            FocusWindowDown,
+	"FocusWindowLeft":            FocusWindowLeft,
+	"FocusWindowRight":           FocusWindowRight,
+	"FocusWindowTopLeft":         FocusWindowTopLeft,
+	"FocusWindowBottomRight":     FocusWindowBottomRight,
+	"FocusWindowPrevious":        FocusWindowPrevious,
+	"MoveWindowTop":              MoveWindowTop,
+	"MoveWindowBottom":           MoveWindowBottom,
+	"MoveWindowLeft":             MoveWindowLeft,
+	"MoveWindowRight":            MoveWindowRight,
+	"Suspend":                    Suspend,
+	"Quit":                       Quit,
+	"QuitAll":                    QuitAll,
+	"Write":                      Write,
+	"WriteQuit":                  WriteQuit,
+	"SaveAs":                     SaveAs,
+	"Update":                     Update,
+	"WriteAll":                   WriteAll,
+	"WriteAllQuit":               WriteAllQuit,
+	"CheckTime":                  CheckTime,
+	"Filter":                     Filter,
+	"Read":                       Read,
+}
+
+// ParseType returns the event type of the name.
+func ParseType(name string) (Type, bool) {
+	t, ok := names[name]
+	return t, ok
+}
@@ -37,6 +37,7 @@ var states = map[int]map[rune]struct {
 // ---+------ . ------+---+-- [-+]num.. --+---
 //    +-- ' -+- < -+--+
 //           +- > -+
+//           +- a-z -+
 func ParsePos(xs []rune, i int) (Position, int) {
 	var state int
 	var position Position
@@ -69,6 +70,10 @@ func ParsePos(xs []rune, i int) (Position, int) {
 			state = 1
 			continue
 		}
+		if state == 2 && 'a' <= xs[i] && xs[i] <= 'z' {
+			position, state = Mark{Name: xs[i]}, 1
+			continue
+		}
 		if s, ok := states[state]; ok {
 			if next, ok := s[xs[i]]; ok {
 				state = next.state
@@ -90,9 +95,9 @@ func parseNum(xs []rune, i int) (int64, int) {
 		c := xs[i]
 		if hex == 0 && c == '0' {
 			hex = 1
-		} else if hex == 1 && c == 'x' {
+		} else if hex == 1 && (c == 'x' || c == 'X') {
 			hex = 2
-		} else if '0' <= c && c <= '9' || hex == 2 && 'a' <= c && c <= 'f' {
+		} else if '0' <= c && c <= '9' || hex == 2 && ('a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
 			if hex == 2 {
 				offset *= 0x10
 			} else {
@@ -101,8 +106,10 @@ func parseNum(xs []rune, i int) (int64, int) {
 			}
 			if '0' <= c && c <= '9' {
 				offset += int64(c - '0')
-			} else {
+			} else if 'a' <= c {
 				offset += int64(c - 'a' + 0x0a)
+			} else {
+				offset += int64(c - 'A' + 0x0a)
 			}
 		} else {
 			return offset, i - 1
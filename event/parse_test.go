@@ -21,6 +21,7 @@ func TestParseRange(t *testing.T) {
 		{"'>", &Range{VisualEnd{}, nil}, 2},
 		{" '<  ,  '>  write", &Range{VisualStart{}, VisualEnd{}}, 12},
 		{" '<+0x10 ,  '>-10 ", &Range{VisualStart{0x10}, VisualEnd{-10}}, 18},
+		{"'a,'b+3", &Range{Mark{'a', 0}, Mark{'b', 3}}, 7},
 	}
 	for _, testCase := range testCases {
 		got, gotIndex := ParseRange([]rune(testCase.target), 0)
@@ -45,6 +46,7 @@ func TestParsePos(t *testing.T) {
 		{"1024,4096", Absolute{1024}, 4},
 		{"1+2+3+4+5+6+7+8+9+0xa+0xb+0xc+0xd+0xe+0xf", Absolute{120}, 41},
 		{"0xffff", Absolute{65535}, 6},
+		{"0X0800ABCD", Absolute{0x0800abcd}, 10},
 		{"+16777216", Relative{16777216}, 9},
 		{"-0xabcdef", Relative{-0xabcdef}, 9},
 		{"+10+20+30-40", Relative{20}, 12},
@@ -54,6 +56,7 @@ func TestParsePos(t *testing.T) {
 		{"'>", VisualEnd{}, 2},
 		{" '<  ,  '> ", VisualStart{}, 5},
 		{" '<+0x10 ,  '>-10 ", VisualStart{0x10}, 9},
+		{"'z-0x10", Mark{'z', -0x10}, 7},
 	}
 	for _, testCase := range testCases {
 		got, gotIndex := ParsePos([]rune(testCase.target), 0)
@@ -66,3 +66,15 @@ func (p VisualEnd) isPosition() {}
 func (p VisualEnd) addOffset(offset int64) Position {
 	return VisualEnd{p.Offset + offset}
 }
+
+// Mark is the position of a mark.
+type Mark struct {
+	Name   rune
+	Offset int64
+}
+
+func (p Mark) isPosition() {}
+
+func (p Mark) addOffset(offset int64) Position {
+	return Mark{p.Name, p.Offset + offset}
+}
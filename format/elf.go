@@ -0,0 +1,25 @@
+package format
+
+import (
+	"debug/elf"
+	"io"
+)
+
+func parseELF(r io.ReaderAt, _ int64) (*Format, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	format := &Format{Name: "ELF", Kind: "section"}
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NULL {
+			continue
+		}
+		size := int64(s.Size)
+		if s.Type == elf.SHT_NOBITS {
+			size = 0
+		}
+		format.Entries = append(format.Entries, Entry{s.Name, int64(s.Offset), size})
+	}
+	return format, nil
+}
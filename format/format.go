@@ -0,0 +1,79 @@
+package format
+
+import (
+	"bytes"
+	"io"
+)
+
+// Format is a file format recognized by the magic bytes.
+type Format struct {
+	Name    string
+	Kind    string
+	Entries []Entry
+}
+
+// Entry is a section, chunk or archive entry of the file.
+type Entry struct {
+	Name   string
+	Offset int64
+	Size   int64
+}
+
+type recognizer struct {
+	magic []byte
+	parse func(io.ReaderAt, int64) (*Format, error)
+}
+
+var recognizers = []recognizer{
+	{[]byte("\x7fELF"), parseELF},
+	{[]byte("MZ"), parsePE},
+	{[]byte("\x89PNG\r\n\x1a\n"), parsePNG},
+	{[]byte("PK\x03\x04"), parseZIP},
+	{[]byte("PK\x05\x06"), parseZIP},
+}
+
+// Recognize detects the format of the file by the magic bytes.
+// It returns nil if the format is unknown or the file is broken.
+func Recognize(r io.ReaderAt, size int64) *Format {
+	magic := make([]byte, 8)
+	n, _ := r.ReadAt(magic, 0)
+	for _, rc := range recognizers {
+		if bytes.HasPrefix(magic[:n], rc.magic) {
+			if f, err := rc.parse(r, size); err == nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Find finds the next entry of the name after the offset.
+// The search wraps around the end of the file.
+func (f *Format) Find(name string, offset int64) (Entry, bool) {
+	var found bool
+	var first Entry
+	for _, e := range f.Entries {
+		if e.Name != name {
+			continue
+		}
+		if e.Offset > offset {
+			return e, true
+		}
+		if !found {
+			first, found = e, true
+		}
+	}
+	return first, found
+}
+
+// Lookup returns the index of the last entry which contains the offset.
+// It returns -1 if no entry contains the offset.
+func (f *Format) Lookup(offset int64) int {
+	index := -1
+	for i, e := range f.Entries {
+		if e.Offset <= offset && offset < e.Offset+e.Size {
+			index = i
+		}
+	}
+	return index
+}
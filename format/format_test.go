@@ -0,0 +1,148 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func pngChunk(typ string, data string) string {
+	bs := make([]byte, 4)
+	binary.BigEndian.PutUint32(bs, uint32(len(data)))
+	return string(bs) + typ + data + "\x00\x00\x00\x00"
+}
+
+func TestRecognizePNG(t *testing.T) {
+	data := "\x89PNG\r\n\x1a\n" + pngChunk("IHDR", "0123456789abc") +
+		pngChunk("IDAT", "xxxx") + pngChunk("IDAT", "yy") + pngChunk("IEND", "")
+	f := Recognize(bytes.NewReader([]byte(data)), int64(len(data)))
+	if f == nil {
+		t.Fatalf("Recognize should detect PNG")
+	}
+	expected := &Format{Name: "PNG", Kind: "chunk", Entries: []Entry{
+		{"IHDR", 8, 25}, {"IDAT", 33, 16}, {"IDAT", 49, 14}, {"IEND", 63, 12},
+	}}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Recognize should return %+v but got %+v", expected, f)
+	}
+	for _, testCase := range []struct {
+		name     string
+		offset   int64
+		expected int64
+	}{
+		{"IDAT", 0, 33},
+		{"IDAT", 33, 49},
+		{"IDAT", 49, 33},
+		{"IEND", 70, 63},
+	} {
+		e, ok := f.Find(testCase.name, testCase.offset)
+		if !ok || e.Offset != testCase.expected {
+			t.Errorf("Find(%q, %d) should return %d but got %d", testCase.name, testCase.offset, testCase.expected, e.Offset)
+		}
+	}
+	if _, ok := f.Find("tEXt", 0); ok {
+		t.Errorf("Find should not find tEXt chunk")
+	}
+	if i := f.Lookup(50); i != 2 {
+		t.Errorf("Lookup(50) should return 2 but got %d", i)
+	}
+	if i := f.Lookup(4); i != -1 {
+		t.Errorf("Lookup(4) should return -1 but got %d", i)
+	}
+}
+
+func TestRecognizeZIP(t *testing.T) {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range []string{"foo.txt", "bar/baz.txt"} {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte("Hello, " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	f := Recognize(bytes.NewReader(data), int64(len(data)))
+	if f == nil {
+		t.Fatalf("Recognize should detect ZIP")
+	}
+	if f.Name != "ZIP" || f.Kind != "entry" || len(f.Entries) != 2 {
+		t.Fatalf("Recognize should return two ZIP entries but got %+v", f)
+	}
+	for _, e := range f.Entries {
+		if got := string(data[e.Offset : e.Offset+e.Size]); got != "Hello, "+e.Name {
+			t.Errorf("entry %s should point to its data but got %q", e.Name, got)
+		}
+	}
+}
+
+func TestRecognizePE(t *testing.T) {
+	data := make([]byte, 0x200)
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint32(data[0x3c:], 0x40)
+	copy(data[0x40:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(data[0x44:], 0x14c) // machine: i386
+	binary.LittleEndian.PutUint16(data[0x46:], 2)     // number of sections
+	for i, name := range []string{".text", ".data"} {
+		s := data[0x58+40*i:]
+		copy(s, name)
+		binary.LittleEndian.PutUint32(s[16:], 0x80)                 // size of raw data
+		binary.LittleEndian.PutUint32(s[20:], uint32(0x100+0x80*i)) // pointer to raw data
+	}
+	f := Recognize(bytes.NewReader(data), int64(len(data)))
+	if f == nil {
+		t.Fatalf("Recognize should detect PE")
+	}
+	expected := &Format{Name: "PE", Kind: "section", Entries: []Entry{
+		{".text", 0x100, 0x80}, {".data", 0x180, 0x80},
+	}}
+	if !reflect.DeepEqual(f, expected) {
+		t.Errorf("Recognize should return %+v but got %+v", expected, f)
+	}
+}
+
+func TestRecognizeELF(t *testing.T) {
+	name, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil || string(magic) != "\x7fELF" {
+		t.Skip("the test binary is not an ELF file")
+	}
+	info, err := r.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Recognize(r, info.Size())
+	if f == nil {
+		t.Fatalf("Recognize should detect ELF")
+	}
+	if f.Name != "ELF" || f.Kind != "section" {
+		t.Errorf("Recognize should return ELF sections but got %+v", f)
+	}
+	if _, ok := f.Find(".text", 0); !ok {
+		t.Errorf("Find should find .text section")
+	}
+}
+
+func TestRecognizeUnknown(t *testing.T) {
+	for _, data := range []string{"", "MZ", "PK\x03\x04broken", "Hello, world!"} {
+		if f := Recognize(bytes.NewReader([]byte(data)), int64(len(data))); f != nil {
+			t.Errorf("Recognize(%q) should return nil but got %+v", data, f)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package format
+
+import (
+	"debug/pe"
+	"io"
+)
+
+func parsePE(r io.ReaderAt, _ int64) (*Format, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	format := &Format{Name: "PE", Kind: "section"}
+	for _, s := range f.Sections {
+		format.Entries = append(format.Entries,
+			Entry{s.Name, int64(s.Offset), int64(s.Size)})
+	}
+	return format, nil
+}
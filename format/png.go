@@ -0,0 +1,31 @@
+package format
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const maxChunks = 100000
+
+func parsePNG(r io.ReaderAt, size int64) (*Format, error) {
+	format := &Format{Name: "PNG", Kind: "chunk"}
+	header := make([]byte, 8)
+	for offset := int64(8); offset+12 <= size; {
+		if len(format.Entries) >= maxChunks {
+			return nil, errors.New("too many chunks")
+		}
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, err
+		}
+		// length (4 bytes), chunk type (4 bytes), data and crc (4 bytes)
+		length := int64(binary.BigEndian.Uint32(header))
+		format.Entries = append(format.Entries,
+			Entry{string(header[4:]), offset, length + 12})
+		offset += length + 12
+	}
+	if len(format.Entries) == 0 {
+		return nil, errors.New("no chunks")
+	}
+	return format, nil
+}
@@ -0,0 +1,23 @@
+package format
+
+import (
+	"archive/zip"
+	"io"
+)
+
+func parseZIP(r io.ReaderAt, size int64) (*Format, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	format := &Format{Name: "ZIP", Kind: "entry"}
+	for _, f := range zr.File {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		format.Entries = append(format.Entries,
+			Entry{f.Name, offset, int64(f.CompressedSize64)})
+	}
+	return format, nil
+}
@@ -1,6 +1,10 @@
 package history
 
-import "github.com/itchyny/bed/buffer"
+import (
+	"io"
+
+	"github.com/itchyny/bed/buffer"
+)
 
 // History manages the buffer history.
 type History struct {
@@ -44,6 +48,21 @@ func (h *History) Undo() (*buffer.Buffer, int, int64, int64) {
 	return e.buffer.Clone(), h.index, e.offset, e.cursor
 }
 
+// Undoable reports whether the history has the previous entry to undo.
+func (h *History) Undoable() bool {
+	return h.index > 0
+}
+
+// Uses reports whether any buffer in the history reads the reader.
+func (h *History) Uses(r io.ReaderAt) bool {
+	for _, e := range h.entries {
+		if e.buffer.Uses(r) {
+			return true
+		}
+	}
+	return false
+}
+
 // Redo the history.
 func (h *History) Redo() (*buffer.Buffer, int64, int64) {
 	if h.index == len(h.entries)-1 || h.index < 0 {
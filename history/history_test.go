@@ -29,6 +29,9 @@ func TestHistoryUndo(t *testing.T) {
 	buffer2 := buffer.NewBuffer(strings.NewReader("test2"))
 	history.Push(buffer2, 3, 2)
 
+	if !history.Undoable() {
+		t.Errorf("history.Undoable should return true")
+	}
 	buf := make([]byte, 8)
 	b, index, offset, cursor = history.Undo()
 	b.Read(buf)
@@ -44,6 +47,9 @@ func TestHistoryUndo(t *testing.T) {
 	if cursor != 1 {
 		t.Errorf("push should return cursor 1 but got %d", cursor)
 	}
+	if history.Undoable() {
+		t.Errorf("history.Undoable should return false")
+	}
 
 	buf = make([]byte, 8)
 	b, offset, cursor = history.Redo()
@@ -2,6 +2,8 @@ package key
 
 import (
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/itchyny/bed/event"
 )
@@ -12,6 +14,16 @@ type Key string
 type keyEvent struct {
 	keys  []Key
 	event event.Type
+	arg   string
+}
+
+// keyMapping is a user-defined key mapping. The mapping emits the event if
+// it is not Nop, or executes the keys on the right hand side.
+type keyMapping struct {
+	keys  []Key
+	event event.Type
+	rhs   string
+	remap bool
 }
 
 const (
@@ -20,11 +32,9 @@ const (
 	keysNeq
 )
 
-func (ke keyEvent) cmp(ks []Key) int {
-	if len(ke.keys) < len(ks) {
-		return keysNeq
-	}
-	for i, k := range ke.keys {
+// cmp checks the key sequence starts with the keys.
+func cmp(keys []Key, ks []Key) int {
+	for i, k := range keys {
 		if i >= len(ks) {
 			return keysPending
 		}
@@ -37,49 +47,314 @@ func (ke keyEvent) cmp(ks []Key) int {
 
 // Manager holds the key mappings and current key sequence.
 type Manager struct {
-	keys   []Key
-	events []keyEvent
-	count  bool
+	keys     []Key
+	held     []bool
+	dropped  []Key
+	events   []keyEvent
+	override []keyEvent
+	mappings []keyMapping
+	count    bool
+	waiting  bool
+	timeout  time.Duration
+	recorder *Recorder
+	mu       *sync.Mutex
 }
 
 // NewManager creates a new Manager.
 func NewManager(count bool) *Manager {
-	return &Manager{count: count}
+	return &Manager{count: count, timeout: time.Second, mu: new(sync.Mutex)}
 }
 
 // Register adds a new key mapping.
 func (km *Manager) Register(eventType event.Type, keys ...Key) {
-	km.events = append(km.events, keyEvent{keys, eventType})
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.events = append(km.events, keyEvent{keys, eventType, ""})
+}
+
+// RegisterArg adds a new key mapping of the event with the argument.
+func (km *Manager) RegisterArg(eventType event.Type, arg string, keys ...Key) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.events = append(km.events, keyEvent{keys, eventType, arg})
+}
+
+// Override makes the keys emit the event without waiting for the longer key
+// sequences, until it is removed by RemoveOverride.
+func (km *Manager) Override(eventType event.Type, keys ...Key) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.removeOverride(keys)
+	km.override = append(km.override, keyEvent{keys, eventType, ""})
+}
+
+// RemoveOverride removes the keys added by Override.
+func (km *Manager) RemoveOverride(keys ...Key) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.removeOverride(keys)
+}
+
+func (km *Manager) removeOverride(keys []Key) {
+	for i, ke := range km.override {
+		if len(ke.keys) == len(keys) && cmp(ke.keys, keys) == keysEq {
+			km.override = append(km.override[:i], km.override[i+1:]...)
+			return
+		}
+	}
+}
+
+// Map adds a user-defined key mapping to the keys on the right hand side,
+// which are executed with the user-defined mappings if remap is true.
+// The right hand side in the form of <Event:Name> emits the event.
+func (km *Manager) Map(keys []Key, rhs string, remap bool) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	m := keyMapping{keys: keys, rhs: rhs, remap: remap}
+	if len(rhs) > 8 && rhs[:7] == "<Event:" && rhs[len(rhs)-1] == '>' {
+		m.event, _ = event.ParseType(rhs[7 : len(rhs)-1])
+	}
+	km.unmap(keys)
+	km.mappings = append(km.mappings, m)
+}
+
+// Unmap removes the user-defined key mapping.
+func (km *Manager) Unmap(keys []Key) bool {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return km.unmap(keys)
+}
+
+func (km *Manager) unmap(keys []Key) bool {
+	for i, m := range km.mappings {
+		if len(m.keys) == len(keys) && cmp(m.keys, keys) == keysEq {
+			km.mappings = append(km.mappings[:i], km.mappings[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Mappings returns the user-defined key mappings starting with the keys,
+// in pairs of the left and right hand sides in the key notation.
+func (km *Manager) Mappings(keys []Key) [][2]string {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	var mappings [][2]string
+	for _, m := range km.mappings {
+		if cmp(keys, m.keys) == keysEq {
+			mappings = append(mappings, [2]string{FormatKeys(m.keys), m.rhs})
+		}
+	}
+	return mappings
+}
+
+// SetTimeout sets the time to wait for the following keys.
+func (km *Manager) SetTimeout(timeout time.Duration) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.timeout = timeout
+}
+
+// Timeout returns the time to wait for the following keys.
+func (km *Manager) Timeout() time.Duration {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return km.timeout
 }
 
-// Press checks the new key down event.
+// Pending returns whether the Manager holds the pressed keys until Flush is
+// called after the timeout; the keys match some mapping while a longer
+// mapping may follow, or the keys are a prefix of a user-defined mapping.
+func (km *Manager) Pending() bool {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return km.waiting && len(km.keys) > 0
+}
+
+// Dropped returns the held keys dropped without matching any mapping since
+// the last call, so that they can be handled as runes.
+func (km *Manager) Dropped() []Key {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	keys := km.dropped
+	km.dropped = nil
+	return keys
+}
+
+// Press checks the new key down event. It holds the keys while the key
+// sequence is ambiguous or a prefix of a user-defined mapping, until Flush
+// is called after the timeout.
 func (km *Manager) Press(k Key) event.Event {
-	km.keys = append(km.keys, k)
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	if km.recorder != nil {
+		km.recorder.record(k)
+	}
+	km.keys, km.held = append(km.keys, k), append(km.held, false)
+	return km.resolve()
+}
+
+// Next checks the keys left after the last event, which occurs when a key
+// resolves the ambiguous keys. It should be called until it returns Nop.
+func (km *Manager) Next() event.Event {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	if len(km.keys) == 0 || km.waiting {
+		return event.Event{Type: event.Nop}
+	}
+	return km.resolve()
+}
+
+func (km *Manager) resolve() event.Event {
 	for i := 0; i < len(km.keys); i++ {
-		keys := km.keys[i:]
-		var count int64
-		if km.count {
-			numStr := ""
-			for j, k := range keys {
-				if len(k) == 1 && ('1' <= k[0] && k[0] <= '9' || k[0] == '0' && j > 0) {
-					numStr += string(k)
-				} else {
-					break
-				}
-			}
-			keys = keys[len(numStr):]
-			count, _ = strconv.ParseInt(numStr, 10, 64)
+		e, n, pending := km.match(km.keys[i:], true)
+		if pending {
+			km.drop(i)
+			km.waiting = n > 0 || km.prefixMapping(km.keys)
+			km.held[len(km.held)-1] = km.held[len(km.held)-1] || km.waiting
+			return event.Event{Type: event.Nop}
+		}
+		if n > 0 {
+			km.drop(i)
+			km.keys, km.held = km.keys[n:], km.held[n:]
+			km.holdAll()
+			return e
+		}
+	}
+	km.drop(len(km.keys))
+	return event.Event{Type: event.Nop}
+}
+
+// Flush emits the event of the held keys, after the timeout. The keys left
+// are checked by Next.
+func (km *Manager) Flush() event.Event {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	e, n, _ := km.match(km.keys, true)
+	if n == 0 {
+		km.drop(len(km.keys))
+		return event.Event{Type: event.Nop}
+	}
+	km.keys, km.held = km.keys[n:], km.held[n:]
+	km.holdAll()
+	return e
+}
+
+// holdAll marks the keys left after the event as held, which are checked by
+// Next and dropped as runes unless they match some mapping.
+func (km *Manager) holdAll() {
+	for i := range km.held {
+		km.held[i] = true
+	}
+	km.waiting = false
+}
+
+// drop removes the first n pending keys.
+func (km *Manager) drop(n int) {
+	for i, k := range km.keys[:n] {
+		if km.held[i] {
+			km.dropped = append(km.dropped, k)
+		}
+	}
+	km.keys, km.held = km.keys[n:], km.held[n:]
+	if len(km.keys) == 0 {
+		km.keys, km.held, km.waiting = nil, nil, false
+	}
+}
+
+func (km *Manager) prefixMapping(keys []Key) bool {
+	_, numLen := km.splitCount(keys)
+	keys = keys[numLen:]
+	for _, m := range km.mappings {
+		if cmp(m.keys, keys) == keysPending {
+			return true
+		}
+	}
+	return false
+}
+
+// Match finds the mapping of the key sequence at the head of the keys, and
+// returns the event and the number of the keys used. The user-defined key
+// mappings are used if remap is true.
+func (km *Manager) Match(keys []Key, remap bool) (event.Event, int) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	e, n, _ := km.match(keys, remap)
+	return e, n
+}
+
+// match finds the longest mapping of the keys. It also reports whether the
+// keys is a prefix of a longer mapping.
+func (km *Manager) match(keys []Key, remap bool) (event.Event, int, bool) {
+	count, numLen := km.splitCount(keys)
+	keys = keys[numLen:]
+	var pending bool
+	for _, ke := range km.override {
+		switch cmp(ke.keys, keys) {
+		case keysPending:
+			pending = true
+		case keysEq:
+			return event.Event{Type: ke.event, Count: count}, numLen + len(ke.keys), false
 		}
-		for _, ke := range km.events {
-			switch ke.cmp(keys) {
+	}
+	var e event.Event
+	var size int
+	if remap {
+		for _, m := range km.mappings {
+			switch cmp(m.keys, keys) {
 			case keysPending:
-				return event.Event{Type: event.Nop}
+				pending = true
 			case keysEq:
-				km.keys = nil
-				return event.Event{Type: ke.event, Count: count}
+				if len(m.keys) > size {
+					e, size = m.toEvent(), len(m.keys)
+				}
 			}
 		}
 	}
-	km.keys = nil
-	return event.Event{Type: event.Nop}
+	for _, ke := range km.events {
+		switch cmp(ke.keys, keys) {
+		case keysPending:
+			pending = true
+		case keysEq:
+			if len(ke.keys) > size {
+				e, size = event.Event{Type: ke.event, Arg: ke.arg}, len(ke.keys)
+				if k := ke.keys[len(ke.keys)-1]; len(k) == 1 {
+					e.Rune = rune(k[0])
+				}
+			}
+		}
+	}
+	if size == 0 {
+		return event.Event{Type: event.Nop}, 0, pending
+	}
+	e.Count = count
+	return e, numLen + size, pending
+}
+
+func (m keyMapping) toEvent() event.Event {
+	if m.event != event.Nop {
+		return event.Event{Type: m.event}
+	}
+	if m.remap {
+		return event.Event{Type: event.ExecuteKeys, Arg: m.rhs}
+	}
+	return event.Event{Type: event.ExecuteKeysNoremap, Arg: m.rhs}
+}
+
+// splitCount returns the count at the head of the keys and its length.
+func (km *Manager) splitCount(keys []Key) (int64, int) {
+	if !km.count {
+		return 0, 0
+	}
+	var numStr string
+	for j, k := range keys {
+		if len(k) == 1 && ('1' <= k[0] && k[0] <= '9' || k[0] == '0' && j > 0) {
+			numStr += string(k)
+		} else {
+			break
+		}
+	}
+	count, _ := strconv.ParseInt(numStr, 10, 64)
+	return count, len(numStr)
 }
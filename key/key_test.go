@@ -19,6 +19,37 @@ func TestKeyManagerPress(t *testing.T) {
 	}
 }
 
+func TestKeyManagerPressRune(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.SetMark, "m", "a")
+	km.Register(event.PageTop, "g", "g")
+	km.Register(event.CursorUp, "up")
+	if e := km.Press("m"); e.Type != event.Nop {
+		t.Errorf("pressing m should be nop but got: %d", e.Type)
+	}
+	if e := km.Press("a"); e.Type != event.SetMark || e.Rune != 'a' {
+		t.Errorf("pressing ma should emit event.SetMark with 'a' but got: %d, %q", e.Type, e.Rune)
+	}
+	km.Press("g")
+	if e := km.Press("g"); e.Type != event.PageTop || e.Rune != 'g' {
+		t.Errorf("pressing gg should emit event.PageTop with 'g' but got: %d, %q", e.Type, e.Rune)
+	}
+	if e := km.Press("up"); e.Type != event.CursorUp || e.Rune != 0 {
+		t.Errorf("pressing up should emit event.CursorUp without rune but got: %d, %q", e.Type, e.Rune)
+	}
+}
+
+func TestKeyManagerPressArg(t *testing.T) {
+	km := NewManager(true)
+	km.RegisterArg(event.FindByte, "4a", "f", "4", "a")
+	km.Press("2")
+	km.Press("f")
+	km.Press("4")
+	if e := km.Press("a"); e.Type != event.FindByte || e.Arg != "4a" || e.Count != 2 {
+		t.Errorf("pressing 2f4a should emit event.FindByte with 4a and count 2 but got: %d, %q, %d", e.Type, e.Arg, e.Count)
+	}
+}
+
 func TestKeyManagerPressMulti(t *testing.T) {
 	km := NewManager(true)
 	km.Register(event.CursorUp, "k", "k", "j")
@@ -42,6 +73,22 @@ func TestKeyManagerPressMulti(t *testing.T) {
 	}
 }
 
+func TestKeyManagerOverride(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.RecordMacro, "q", "a")
+	km.Override(event.StopRecordMacro, "q")
+	if e := km.Press("q"); e.Type != event.StopRecordMacro {
+		t.Errorf("pressing q should emit event.StopRecordMacro but got: %d", e.Type)
+	}
+	km.RemoveOverride("q")
+	if e := km.Press("q"); e.Type != event.Nop {
+		t.Errorf("pressing q should be nop but got: %d", e.Type)
+	}
+	if e := km.Press("a"); e.Type != event.RecordMacro || e.Rune != 'a' {
+		t.Errorf("pressing qa should emit event.RecordMacro with 'a' but got: %d, %q", e.Type, e.Rune)
+	}
+}
+
 func TestKeyManagerPressCount(t *testing.T) {
 	km := NewManager(true)
 	km.Register(event.CursorUp, "k", "j")
@@ -69,3 +116,126 @@ func TestKeyManagerPressCount(t *testing.T) {
 		t.Errorf("pressing 37kj should emit event.CursorUp with count 37 but got: %d", e.Count)
 	}
 }
+
+func TestKeyManagerMap(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.CursorDown, "j")
+	km.Register(event.CursorUp, "k")
+	km.Map([]Key{"\\", "w"}, ":w<CR>", true)
+	km.Map([]Key{"j", "k"}, "<Esc>", false)
+	km.Map([]Key{"g", "x"}, "<Event:CursorNext>", true)
+	if e := km.Press("\\"); e.Type != event.Nop || !km.Pending() {
+		t.Errorf("pressing \\ should be pending but got: %d", e.Type)
+	}
+	if e := km.Press("w"); e.Type != event.ExecuteKeys || e.Arg != ":w<CR>" {
+		t.Errorf("pressing \\w should emit event.ExecuteKeys but got: %+v", e)
+	}
+	if e := km.Press("j"); e.Type != event.Nop || !km.Pending() {
+		t.Errorf("pressing j should be pending but got: %d", e.Type)
+	}
+	if e := km.Press("k"); e.Type != event.ExecuteKeysNoremap || e.Arg != "<Esc>" {
+		t.Errorf("pressing jk should emit event.ExecuteKeysNoremap but got: %+v", e)
+	}
+	km.Press("3")
+	km.Press("g")
+	if e := km.Press("x"); e.Type != event.CursorNext || e.Count != 3 {
+		t.Errorf("pressing 3gx should emit event.CursorNext with count 3 but got: %+v", e)
+	}
+	if mappings := km.Mappings([]Key{"j"}); len(mappings) != 1 ||
+		mappings[0] != [2]string{"jk", "<Esc>"} {
+		t.Errorf("mappings should be jk but got: %v", mappings)
+	}
+	if !km.Unmap([]Key{"j", "k"}) || km.Unmap([]Key{"j", "k"}) {
+		t.Errorf("jk should be unmapped only once")
+	}
+	if e := km.Press("j"); e.Type != event.CursorDown {
+		t.Errorf("pressing j should emit event.CursorDown but got: %d", e.Type)
+	}
+}
+
+func TestKeyManagerFlush(t *testing.T) {
+	km := NewManager(false)
+	km.Register(event.CursorDown, "j")
+	km.Map([]Key{"j", "k"}, "<Esc>", false)
+	km.Map([]Key{"k", "k"}, "<Esc>", false)
+	km.Press("j")
+	if e := km.Flush(); e.Type != event.CursorDown {
+		t.Errorf("flushing j should emit event.CursorDown but got: %d", e.Type)
+	}
+	if keys := km.Dropped(); len(keys) != 0 {
+		t.Errorf("no keys should be dropped but got: %v", keys)
+	}
+	km.Press("k")
+	if e := km.Flush(); e.Type != event.Nop {
+		t.Errorf("flushing k should be nop but got: %d", e.Type)
+	}
+	if keys := km.Dropped(); len(keys) != 1 || keys[0] != "k" {
+		t.Errorf("k should be dropped but got: %v", keys)
+	}
+	km.Press("k")
+	km.Press("j")
+	if keys := km.Dropped(); len(keys) != 1 || keys[0] != "k" {
+		t.Errorf("k should be dropped but got: %v", keys)
+	}
+	if e := km.Press("k"); e.Type != event.ExecuteKeysNoremap {
+		t.Errorf("pressing jk should emit event.ExecuteKeysNoremap but got: %d", e.Type)
+	}
+	if km.Pending() {
+		t.Errorf("no keys should be pending")
+	}
+}
+
+func TestKeyManagerMatch(t *testing.T) {
+	km := NewManager(true)
+	km.Register(event.CursorDown, "j")
+	km.Map([]Key{"j"}, "k", true)
+	if e, n := km.Match([]Key{"1", "2", "j", "j"}, true); e.Type != event.ExecuteKeys || e.Count != 12 || n != 3 {
+		t.Errorf("matching 12jj should emit event.ExecuteKeys with count 12 but got: %+v, %d", e, n)
+	}
+	if e, n := km.Match([]Key{"j"}, false); e.Type != event.CursorDown || n != 1 {
+		t.Errorf("matching j without remap should emit event.CursorDown but got: %+v, %d", e, n)
+	}
+	if e, n := km.Match([]Key{"x"}, true); e.Type != event.Nop || n != 0 {
+		t.Errorf("matching x should be nop but got: %+v, %d", e, n)
+	}
+}
+
+func TestKeyManagerNext(t *testing.T) {
+	km := NewManager(false)
+	km.Register(event.StopRecordMacro, "q")
+	km.Register(event.RecordMacro, "q", "a")
+	km.Register(event.CursorDown, "j")
+	if e := km.Press("q"); e.Type != event.Nop || !km.Pending() {
+		t.Errorf("pressing q should be pending but got: %d", e.Type)
+	}
+	if e := km.Press("j"); e.Type != event.StopRecordMacro {
+		t.Errorf("pressing qj should emit event.StopRecordMacro but got: %d", e.Type)
+	}
+	if e := km.Next(); e.Type != event.CursorDown {
+		t.Errorf("the key left should emit event.CursorDown but got: %d", e.Type)
+	}
+	if e := km.Next(); e.Type != event.Nop {
+		t.Errorf("no keys should be left but got: %d", e.Type)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	km := NewManager(false)
+	km.SetRecorder(r)
+	km.Register(event.CursorDown, "j")
+	km.Press("j")
+	r.Start()
+	if !r.Recording() {
+		t.Errorf("recorder should be recording")
+	}
+	km.Press("j")
+	km.Press("x")
+	if keys := r.Stop(); len(keys) != 2 || keys[0] != "j" || keys[1] != "x" {
+		t.Errorf("recorded keys should be jx but got: %v", keys)
+	}
+	km.Press("j")
+	if r.Recording() || len(r.Stop()) != 0 {
+		t.Errorf("recorder should not be recording")
+	}
+}
@@ -0,0 +1,121 @@
+package key
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// specialKeys are the names of the keys in the angle brackets.
+var specialKeys = map[string]Key{
+	"cr":       "enter",
+	"enter":    "enter",
+	"return":   "enter",
+	"esc":      "escape",
+	"tab":      "tab",
+	"s-tab":    "backtab",
+	"bs":       "backspace2",
+	"del":      "delete",
+	"insert":   "insert",
+	"home":     "home",
+	"end":      "end",
+	"pageup":   "pgup",
+	"pagedown": "pgdn",
+	"up":       "up",
+	"down":     "down",
+	"left":     "left",
+	"right":    "right",
+	"space":    " ",
+	"lt":       "<",
+	"bar":      "|",
+	"bslash":   "\\",
+	"c-h":      "backspace",
+	"c-i":      "tab",
+	"c-m":      "enter",
+	"c-[":      "escape",
+}
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		if _, ok := specialKeys["c-"+string(c)]; !ok {
+			specialKeys["c-"+string(c)] = Key("c-" + string(c))
+		}
+	}
+	for i := 1; i <= 12; i++ {
+		name := "f" + strconv.Itoa(i)
+		specialKeys[name] = Key(name)
+	}
+}
+
+// ParseKeys parses the key notation like <C-w>j or :w<CR>. The <Leader> is
+// replaced with the keys of the leader, which is also in the key notation.
+// The angle brackets of unknown names are parsed as they are.
+func ParseKeys(s, leader string) ([]Key, error) {
+	var keys []Key
+	for i := 0; i < len(s); {
+		if s[i] == '<' {
+			if j := strings.IndexByte(s[i:], '>'); j > 0 {
+				name := strings.ToLower(s[i+1 : i+j])
+				if name == "leader" {
+					if strings.Contains(strings.ToLower(leader), "<leader>") {
+						return nil, errors.New("invalid leader: " + leader)
+					}
+					ks, err := ParseKeys(leader, "")
+					if err != nil {
+						return nil, err
+					}
+					keys, i = append(keys, ks...), i+j+1
+					continue
+				}
+				if k, ok := specialKeys[name]; ok {
+					keys, i = append(keys, k), i+j+1
+					continue
+				}
+			}
+		}
+		r := []rune(s[i:])[0]
+		keys, i = append(keys, Key(string(r))), i+len(string(r))
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("empty keys")
+	}
+	return keys, nil
+}
+
+// FormatKeys formats the keys in the key notation.
+func FormatKeys(keys []Key) string {
+	var sb strings.Builder
+	for _, k := range keys {
+		switch k {
+		case "enter":
+			sb.WriteString("<CR>")
+		case "escape":
+			sb.WriteString("<Esc>")
+		case "backtab":
+			sb.WriteString("<S-Tab>")
+		case "backspace2":
+			sb.WriteString("<BS>")
+		case "backspace":
+			sb.WriteString("<C-h>")
+		case "delete":
+			sb.WriteString("<Del>")
+		case "pgup":
+			sb.WriteString("<PageUp>")
+		case "pgdn":
+			sb.WriteString("<PageDown>")
+		case " ":
+			sb.WriteString("<Space>")
+		case "<":
+			sb.WriteString("<lt>")
+		default:
+			if len([]rune(string(k))) == 1 {
+				sb.WriteString(string(k))
+			} else if strings.HasPrefix(string(k), "c-") {
+				sb.WriteString("<C-" + string(k[2:]) + ">")
+			} else {
+				sb.WriteString("<" + strings.ToUpper(string(k[:1])) + string(k[1:]) + ">")
+			}
+		}
+	}
+	return sb.String()
+}
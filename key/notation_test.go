@@ -0,0 +1,55 @@
+package key
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		str, leader string
+		keys        []Key
+		err         string
+	}{
+		{"jk", `\`, []Key{"j", "k"}, ""},
+		{"<C-w>j", `\`, []Key{"c-w", "j"}, ""},
+		{":w<CR>", `\`, []Key{":", "w", "enter"}, ""},
+		{"<Leader>w", `\`, []Key{"\\", "w"}, ""},
+		{"<leader>w", ",", []Key{",", "w"}, ""},
+		{"<Leader>w", "<Space>", []Key{" ", "w"}, ""},
+		{"<Esc><lt>x<F12>", `\`, []Key{"escape", "<", "x", "f12"}, ""},
+		{"<foo>あ", `\`, []Key{"<", "f", "o", "o", ">", "あ"}, ""},
+		{"", `\`, nil, "empty keys"},
+		{"<Leader>", "<Leader>", nil, "invalid leader: <Leader>"},
+	}
+	for _, tc := range testCases {
+		keys, err := ParseKeys(tc.str, tc.leader)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("ParseKeys(%q) should not fail but got: %v", tc.str, err)
+			}
+			if !reflect.DeepEqual(keys, tc.keys) {
+				t.Errorf("ParseKeys(%q) should be %v but got: %v", tc.str, tc.keys, keys)
+			}
+		} else if err == nil || err.Error() != tc.err {
+			t.Errorf("ParseKeys(%q) should fail with %q but got: %v", tc.str, tc.err, err)
+		}
+	}
+}
+
+func TestFormatKeys(t *testing.T) {
+	testCases := []struct {
+		keys []Key
+		str  string
+	}{
+		{[]Key{"j", "k"}, "jk"},
+		{[]Key{"c-w", "j"}, "<C-w>j"},
+		{[]Key{":", "w", "enter"}, ":w<CR>"},
+		{[]Key{" ", "<", "escape", "f1", "pgdn"}, "<Space><lt><Esc><F1><PageDown>"},
+	}
+	for _, tc := range testCases {
+		if str := FormatKeys(tc.keys); str != tc.str {
+			t.Errorf("FormatKeys(%v) should be %q but got: %q", tc.keys, tc.str, str)
+		}
+	}
+}
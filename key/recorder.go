@@ -0,0 +1,54 @@
+package key
+
+import "sync"
+
+// Recorder records the pressed keys for macros. It is shared by the Managers
+// of the modes so that the keys are recorded across the modes.
+type Recorder struct {
+	keys      []Key
+	recording bool
+	mu        *sync.Mutex
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{mu: new(sync.Mutex)}
+}
+
+// Start starts recording the keys.
+func (r *Recorder) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys, r.recording = nil, true
+}
+
+// Stop stops recording and returns the recorded keys.
+func (r *Recorder) Stop() []Key {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := r.keys
+	r.keys, r.recording = nil, false
+	return keys
+}
+
+// Recording returns whether the Recorder is recording the keys.
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recording
+}
+
+func (r *Recorder) record(k Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording {
+		r.keys = append(r.keys, k)
+	}
+}
+
+// SetRecorder sets the Recorder to record the pressed keys.
+func (km *Manager) SetRecorder(r *Recorder) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.recorder = r
+}
@@ -9,6 +9,7 @@ const (
 	Insert
 	Replace
 	Visual
+	OperatorPending
 	Cmdline
 	Search
 )
@@ -0,0 +1,241 @@
+package option
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of an option value.
+type Type int
+
+// Option types
+const (
+	Bool Type = iota
+	Int
+	String
+)
+
+// Scope is the scope of an option.
+type Scope int
+
+// Option scopes
+const (
+	Global Scope = iota
+	Local
+)
+
+// Option is the definition of an option.
+type Option struct {
+	Name    string
+	Type    Type
+	Scope   Scope
+	Default interface{}
+	Values  []string // the candidates of the value
+	valid   func(interface{}) bool
+}
+
+// Lookup returns the option of the name.
+func Lookup(name string) *Option {
+	for _, o := range Options {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the options.
+func Names() []string {
+	names := make([]string, len(Options))
+	for i, o := range Options {
+		names[i] = o.Name
+	}
+	return names
+}
+
+// Parse parses the value of the option.
+func (o *Option) Parse(value string) (interface{}, error) {
+	var v interface{}
+	switch o.Type {
+	case Bool:
+		return nil, fmt.Errorf("invalid argument: %s=%s", o.Name, value)
+	case Int:
+		n, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %s", o.Name, value)
+		}
+		v = n
+	default:
+		v = value
+	}
+	if !o.isValid(v) {
+		return nil, fmt.Errorf("invalid value for %s: %s", o.Name, value)
+	}
+	return v, nil
+}
+
+// isValid checks the value, which should be one of the candidates if the
+// option has no validator.
+func (o *Option) isValid(v interface{}) bool {
+	if o.valid != nil {
+		return o.valid(v)
+	}
+	if o.Values != nil {
+		for _, value := range o.Values {
+			if value == fmt.Sprint(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Format returns the string representation of the option value.
+func (o *Option) Format(value interface{}) string {
+	if o.Type == Bool {
+		if value == true {
+			return o.Name
+		}
+		return "no" + o.Name
+	}
+	return fmt.Sprintf("%s=%v", o.Name, value)
+}
+
+// Op is the operation of a setting.
+type Op int
+
+// Setting operations
+const (
+	Assign Op = iota
+	Query
+	Toggle
+)
+
+// Setting is an argument of :set.
+type Setting struct {
+	Option *Option
+	Op     Op
+	Value  interface{}
+}
+
+// Parse parses the arguments of :set.
+//
+//	opt      sets the boolean option, or shows the value of other options
+//	noopt    resets the boolean option
+//	invopt   toggles the boolean option, as well as opt!
+//	opt=val  sets the value of the option
+//	opt?     shows the value of the option
+//
+// The white spaces in the value are escaped by a backslash.
+func Parse(arg string) ([]Setting, error) {
+	var settings []Setting
+	for _, arg := range fields(arg) {
+		s, err := parseSetting(arg)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+	return settings, nil
+}
+
+// fields splits the arguments by the white spaces, which are not escaped.
+func fields(arg string) []string {
+	var args []string
+	var sb strings.Builder
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; c {
+		case ' ', '\t':
+			if sb.Len() > 0 {
+				args = append(args, sb.String())
+				sb.Reset()
+			}
+		case '\\':
+			if i+1 < len(arg) && (arg[i+1] == ' ' || arg[i+1] == '\t') {
+				i++
+				c = arg[i]
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if sb.Len() > 0 {
+		args = append(args, sb.String())
+	}
+	return args
+}
+
+func parseSetting(arg string) (Setting, error) {
+	if i := strings.IndexByte(arg, '='); i >= 0 {
+		o := Lookup(arg[:i])
+		if o == nil {
+			return Setting{}, fmt.Errorf("unknown option: %s", arg[:i])
+		}
+		v, err := o.Parse(arg[i+1:])
+		if err != nil {
+			return Setting{}, err
+		}
+		return Setting{Option: o, Op: Assign, Value: v}, nil
+	}
+	if strings.HasSuffix(arg, "?") {
+		o := Lookup(arg[:len(arg)-1])
+		if o == nil {
+			return Setting{}, fmt.Errorf("unknown option: %s", arg[:len(arg)-1])
+		}
+		return Setting{Option: o, Op: Query}, nil
+	}
+	if strings.HasSuffix(arg, "!") {
+		if o := Lookup(arg[:len(arg)-1]); o != nil && o.Type == Bool {
+			return Setting{Option: o, Op: Toggle}, nil
+		}
+		return Setting{}, fmt.Errorf("invalid argument: %s", arg)
+	}
+	if o := Lookup(arg); o != nil {
+		if o.Type == Bool {
+			return Setting{Option: o, Op: Assign, Value: true}, nil
+		}
+		return Setting{Option: o, Op: Query}, nil
+	}
+	if o := Lookup(strings.TrimPrefix(arg, "no")); o != nil && o.Type == Bool {
+		return Setting{Option: o, Op: Assign, Value: false}, nil
+	}
+	if o := Lookup(strings.TrimPrefix(arg, "inv")); o != nil && o.Type == Bool {
+		return Setting{Option: o, Op: Toggle}, nil
+	}
+	return Setting{}, fmt.Errorf("unknown option: %s", arg)
+}
+
+// Values holds the values of the options which differ from the defaults.
+type Values map[string]interface{}
+
+// Get returns the value of the option.
+func (v Values) Get(name string) interface{} {
+	if value, ok := v[name]; ok {
+		return value
+	}
+	if o := Lookup(name); o != nil {
+		return o.Default
+	}
+	return nil
+}
+
+// Bool returns the value of the boolean option.
+func (v Values) Bool(name string) bool {
+	b, _ := v.Get(name).(bool)
+	return b
+}
+
+// Int returns the value of the integer option.
+func (v Values) Int(name string) int64 {
+	n, _ := v.Get(name).(int64)
+	return n
+}
+
+// String returns the value of the string option.
+func (v Values) String(name string) string {
+	s, _ := v.Get(name).(string)
+	return s
+}
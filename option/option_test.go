@@ -0,0 +1,77 @@
+package option
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	defer func(options []*Option) { Options = options }(Options)
+	Options = append(Options, &Option{Name: "test", Type: Bool, Scope: Global, Default: false})
+	for _, testCase := range []struct {
+		arg      string
+		expected []Setting
+		err      string
+	}{
+		{"columns=16", []Setting{{Lookup("columns"), Assign, int64(16)}}, ""},
+		{"group=0x4 display=binary", []Setting{
+			{Lookup("group"), Assign, int64(4)}, {Lookup("display"), Assign, "binary"},
+		}, ""},
+		{"columns", []Setting{{Lookup("columns"), Query, nil}}, ""},
+		{"  display?  ", []Setting{{Lookup("display"), Query, nil}}, ""},
+		{"test notest", []Setting{{Lookup("test"), Assign, true}, {Lookup("test"), Assign, false}}, ""},
+		{"invtest test! test?", []Setting{
+			{Lookup("test"), Toggle, nil}, {Lookup("test"), Toggle, nil}, {Lookup("test"), Query, nil},
+		}, ""},
+		{"foo", nil, "unknown option: foo"},
+		{"foo=1", nil, "unknown option: foo"},
+		{"foo?", nil, "unknown option: foo"},
+		{"nocolumns", nil, "unknown option: nocolumns"},
+		{"columns!", nil, "invalid argument: columns!"},
+		{"test=1", nil, "invalid argument: test=1"},
+		{"columns=x", nil, "invalid value for columns: x"},
+		{"columns=2048", nil, "invalid value for columns: 2048"},
+		{"group=3", nil, "invalid value for group: 3"},
+		{"encoding=EUC-JP", []Setting{{Lookup("encoding"), Assign, "EUC-JP"}}, ""},
+		{"encoding=utf-32", nil, "invalid value for encoding: utf-32"},
+		{"offsetformat=oct", nil, "invalid value for offsetformat: oct"},
+		{`clipboardcopy=xclip\ -i clipboardformat=raw`, []Setting{
+			{Lookup("clipboardcopy"), Assign, "xclip -i"}, {Lookup("clipboardformat"), Assign, "raw"},
+		}, ""},
+		{`mapleader=\`, []Setting{{Lookup("mapleader"), Assign, `\`}}, ""},
+	} {
+		settings, err := Parse(testCase.arg)
+		if testCase.err != "" {
+			if err == nil || err.Error() != testCase.err {
+				t.Errorf("Parse(%q) should return error %q but got %v", testCase.arg, testCase.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if !reflect.DeepEqual(settings, testCase.expected) {
+			t.Errorf("Parse(%q) should be %+v but got %+v", testCase.arg, testCase.expected, settings)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	defer func(options []*Option) { Options = options }(Options)
+	Options = append(Options, &Option{Name: "test", Type: Bool, Scope: Global, Default: true})
+	values := make(Values)
+	if values.Int("group") != 1 || values.String("display") != "hex" || !values.Bool("test") {
+		t.Errorf("values should be the defaults but got %v, %v and %v",
+			values.Int("group"), values.String("display"), values.Bool("test"))
+	}
+	values["group"], values["test"] = int64(4), false
+	if values.Int("group") != 4 || values.Bool("test") {
+		t.Errorf("values should be updated but got %v and %v", values.Int("group"), values.Bool("test"))
+	}
+	if got := Lookup("test").Format(false); got != "notest" {
+		t.Errorf("Format should return %q but got %q", "notest", got)
+	}
+	if got := Lookup("group").Format(int64(4)); got != "group=4" {
+		t.Errorf("Format should return %q but got %q", "group=4", got)
+	}
+}
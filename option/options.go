@@ -0,0 +1,61 @@
+package option
+
+import (
+	"strings"
+
+	"github.com/itchyny/bed/charset"
+)
+
+// Options are the definitions of the options.
+var Options = []*Option{
+	{
+		Name: "autoread", Type: Bool, Scope: Global, Default: false,
+	},
+	{
+		Name: "base", Type: Int, Scope: Local, Default: int64(0),
+		valid: func(v interface{}) bool { return v.(int64) >= 0 },
+	},
+	{
+		Name: "clipboardcopy", Type: String, Scope: Global, Default: "",
+	},
+	{
+		Name: "clipboardformat", Type: String, Scope: Global, Default: "hex",
+		Values: []string{"hex", "raw"},
+	},
+	{
+		Name: "clipboardpaste", Type: String, Scope: Global, Default: "",
+	},
+	{
+		Name: "columns", Type: Int, Scope: Local, Default: int64(0),
+		valid: func(v interface{}) bool { return 0 <= v.(int64) && v.(int64) <= 1024 },
+	},
+	{
+		Name: "display", Type: String, Scope: Local, Default: "hex",
+		Values: []string{"hex", "binary", "octal", "decimal", "signed"},
+	},
+	{
+		Name: "encoding", Type: String, Scope: Local, Default: "ascii",
+		Values: charset.Names(),
+		valid: func(v interface{}) bool {
+			return charset.Lookup(strings.ToLower(v.(string))) != nil
+		},
+	},
+	{
+		Name: "follow", Type: Bool, Scope: Local, Default: false,
+	},
+	{
+		Name: "group", Type: Int, Scope: Local, Default: int64(1),
+		Values: []string{"1", "2", "4", "8"},
+	},
+	{
+		Name: "mapleader", Type: String, Scope: Global, Default: `\`,
+	},
+	{
+		Name: "offsetformat", Type: String, Scope: Local, Default: "hex",
+		Values: []string{"hex", "dec", "both"},
+	},
+	{
+		Name: "timeoutlen", Type: Int, Scope: Global, Default: int64(1000),
+		valid: func(v interface{}) bool { return v.(int64) >= 0 },
+	},
+}
@@ -0,0 +1,86 @@
+package register
+
+import (
+	"fmt"
+	"sync"
+	"unicode"
+)
+
+// Registers holds the bytes of the named registers, which are shared by the
+// yanking and the macros.
+type Registers struct {
+	regs      map[rune][]byte
+	clipboard Clipboard
+	mu        *sync.Mutex
+}
+
+// Clipboard is the system clipboard backing the clipboard register (+ or *).
+// Paste returns nil bytes if the clipboard cannot be read, then the register
+// returns the bytes copied last.
+type Clipboard interface {
+	Copy([]byte) error
+	Paste() ([]byte, error)
+}
+
+// New creates new Registers.
+func New() *Registers {
+	return &Registers{regs: make(map[rune][]byte), mu: new(sync.Mutex)}
+}
+
+// SetClipboard sets the system clipboard.
+func (r *Registers) SetClipboard(clipboard Clipboard) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clipboard = clipboard
+}
+
+// Valid checks the name of the register; the unnamed register ("), the
+// numbered registers (0-9), the named registers (a-z, or A-Z to append) and
+// the clipboard registers (+ and *).
+func Valid(name rune) bool {
+	return name == '"' || '0' <= name && name <= '9' ||
+		'a' <= name && name <= 'z' || 'A' <= name && name <= 'Z' ||
+		name == '+' || name == '*'
+}
+
+// Get returns the bytes of the register.
+func (r *Registers) Get(name rune) ([]byte, error) {
+	if !Valid(name) {
+		return nil, fmt.Errorf("invalid register: %c", name)
+	}
+	if name == '*' {
+		name = '+'
+	}
+	r.mu.Lock()
+	bs, clipboard := r.regs[unicode.ToLower(name)], r.clipboard
+	r.mu.Unlock()
+	if name == '+' && clipboard != nil {
+		if cs, err := clipboard.Paste(); err != nil || cs != nil {
+			return cs, err
+		}
+	}
+	return bs, nil
+}
+
+// Set stores the bytes to the register. The bytes are appended to the named
+// register if the name is uppercase.
+func (r *Registers) Set(name rune, bs []byte) error {
+	if !Valid(name) {
+		return fmt.Errorf("invalid register: %c", name)
+	}
+	if name == '*' {
+		name = '+'
+	}
+	r.mu.Lock()
+	if unicode.IsUpper(name) {
+		name = unicode.ToLower(name)
+		bs = append(append([]byte{}, r.regs[name]...), bs...)
+	}
+	r.regs[name] = bs
+	clipboard := r.clipboard
+	r.mu.Unlock()
+	if name == '+' && clipboard != nil {
+		return clipboard.Copy(bs)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package register
+
+import "testing"
+
+func TestRegisters(t *testing.T) {
+	r := New()
+	if bs, err := r.Get('a'); err != nil || bs != nil {
+		t.Errorf("register a should be empty but got: %q, %v", bs, err)
+	}
+	if err := r.Set('a', []byte("foo")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := r.Set('A', []byte("bar")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if bs, err := r.Get('a'); err != nil || string(bs) != "foobar" {
+		t.Errorf("register a should be %q but got: %q, %v", "foobar", bs, err)
+	}
+	if bs, err := r.Get('A'); err != nil || string(bs) != "foobar" {
+		t.Errorf("register A should be %q but got: %q, %v", "foobar", bs, err)
+	}
+	if err := r.Set('"', []byte("baz")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if bs, err := r.Get('"'); err != nil || string(bs) != "baz" {
+		t.Errorf("register \" should be %q but got: %q, %v", "baz", bs, err)
+	}
+	if err := r.Set('!', nil); err == nil || err.Error() != "invalid register: !" {
+		t.Errorf("err should be %q but got: %v", "invalid register: !", err)
+	}
+	if _, err := r.Get('#'); err == nil || err.Error() != "invalid register: #" {
+		t.Errorf("err should be %q but got: %v", "invalid register: #", err)
+	}
+}
+
+type testClipboard struct {
+	bs []byte
+}
+
+func (c *testClipboard) Copy(bs []byte) error {
+	c.bs = bs
+	return nil
+}
+
+func (c *testClipboard) Paste() ([]byte, error) {
+	return c.bs, nil
+}
+
+func TestRegistersClipboard(t *testing.T) {
+	r := New()
+	if err := r.Set('+', []byte("foo")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if bs, err := r.Get('*'); err != nil || string(bs) != "foo" {
+		t.Errorf("register * should be %q but got: %q, %v", "foo", bs, err)
+	}
+	c := new(testClipboard)
+	r.SetClipboard(c)
+	if bs, err := r.Get('+'); err != nil || string(bs) != "foo" {
+		t.Errorf("register + should be %q but got: %q, %v", "foo", bs, err)
+	}
+	if err := r.Set('*', []byte("bar")); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if string(c.bs) != "bar" {
+		t.Errorf("clipboard should be %q but got: %q", "bar", c.bs)
+	}
+	c.bs = []byte("baz")
+	if bs, err := r.Get('+'); err != nil || string(bs) != "baz" {
+		t.Errorf("register + should be %q but got: %q, %v", "baz", bs, err)
+	}
+}
@@ -0,0 +1,117 @@
+package searcher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/itchyny/bed/mathutil"
+)
+
+const chunkSize = 1024 * 1024
+
+// Searcher searches a pattern in a reader chunk by chunk.
+type Searcher struct {
+	r       io.ReaderAt
+	pattern []byte
+}
+
+// NewSearcher creates a new Searcher.
+func NewSearcher(r io.ReaderAt, pattern []byte) *Searcher {
+	return &Searcher{r: r, pattern: pattern}
+}
+
+// Forward searches the first match which starts in [from, to).
+// It returns -1 if the pattern is not found.
+func (s *Searcher) Forward(from, to int64) (int64, error) {
+	if len(s.pattern) == 0 {
+		return -1, errors.New("empty pattern")
+	}
+	overlap := int64(len(s.pattern) - 1)
+	for base := from; base < to; base += chunkSize {
+		size := mathutil.MinInt64(chunkSize, to-base) + overlap
+		bs, err := s.readAt(base, size)
+		if err != nil {
+			return -1, err
+		}
+		if i := bytes.Index(bs, s.pattern); i >= 0 && base+int64(i) < to {
+			return base + int64(i), nil
+		}
+		if int64(len(bs)) < size {
+			break
+		}
+	}
+	return -1, nil
+}
+
+// Backward searches the last match which lies in [from, to).
+// It returns -1 if the pattern is not found.
+func (s *Searcher) Backward(from, to int64) (int64, error) {
+	if len(s.pattern) == 0 {
+		return -1, errors.New("empty pattern")
+	}
+	overlap := int64(len(s.pattern) - 1)
+	for end := to; end > from; end -= chunkSize {
+		base := mathutil.MaxInt64(end-chunkSize-overlap, from)
+		bs, err := s.readAt(base, end-base)
+		if err != nil {
+			return -1, err
+		}
+		if i := bytes.LastIndex(bs, s.pattern); i >= 0 {
+			return base + int64(i), nil
+		}
+	}
+	return -1, nil
+}
+
+// All calls the function with the offsets of the non-overlapping matches
+// which start in [from, to). The search stops when the function returns false.
+func (s *Searcher) All(from, to int64, fn func(int64) bool) error {
+	if len(s.pattern) == 0 {
+		return errors.New("empty pattern")
+	}
+	overlap := int64(len(s.pattern) - 1)
+	next := from
+	for base := from; base < to; base += chunkSize {
+		size := mathutil.MinInt64(chunkSize, to-base) + overlap
+		bs, err := s.readAt(base, size)
+		if err != nil {
+			return err
+		}
+		for i := mathutil.MaxInt64(next-base, 0); i < int64(len(bs)); {
+			j := bytes.Index(bs[i:], s.pattern)
+			if j < 0 || base+i+int64(j) >= mathutil.MinInt64(base+chunkSize, to) {
+				break
+			}
+			offset := base + i + int64(j)
+			if !fn(offset) {
+				return nil
+			}
+			next = offset + int64(len(s.pattern))
+			i = next - base
+		}
+		if int64(len(bs)) < size {
+			break
+		}
+	}
+	return nil
+}
+
+// Count returns the number of non-overlapping matches which start in [from, to).
+func (s *Searcher) Count(from, to int64) (int64, error) {
+	var count int64
+	err := s.All(from, to, func(int64) bool {
+		count++
+		return true
+	})
+	return count, err
+}
+
+func (s *Searcher) readAt(offset, size int64) ([]byte, error) {
+	bs := make([]byte, size)
+	n, err := s.r.ReadAt(bs, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return bs[:n], nil
+}
@@ -0,0 +1,89 @@
+package searcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearcherForward(t *testing.T) {
+	r := strings.NewReader(strings.Repeat("x", chunkSize-2) + "abcde" + strings.Repeat("x", chunkSize) + "abc")
+	for _, testCase := range []struct {
+		pattern  string
+		from, to int64
+		expected int64
+	}{
+		{"abc", 0, 3 * chunkSize, chunkSize - 2},
+		{"cde", 0, 3 * chunkSize, chunkSize},
+		{"abc", chunkSize - 1, 3 * chunkSize, 2*chunkSize + 3},
+		{"abc", chunkSize - 1, 2 * chunkSize, -1},
+		{"abc", 0, chunkSize - 2, -1},
+		{"abc", 0, chunkSize - 1, chunkSize - 2},
+		{"xyz", 0, 3 * chunkSize, -1},
+	} {
+		got, err := NewSearcher(r, []byte(testCase.pattern)).Forward(testCase.from, testCase.to)
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if got != testCase.expected {
+			t.Errorf("Forward(%q, %d, %d) should return %d but got %d",
+				testCase.pattern, testCase.from, testCase.to, testCase.expected, got)
+		}
+	}
+}
+
+func TestSearcherBackward(t *testing.T) {
+	r := strings.NewReader("abc" + strings.Repeat("x", chunkSize) + "abcde" + strings.Repeat("x", chunkSize))
+	for _, testCase := range []struct {
+		pattern  string
+		from, to int64
+		expected int64
+	}{
+		{"abc", 0, 3 * chunkSize, chunkSize + 3},
+		{"cde", 0, 3 * chunkSize, chunkSize + 5},
+		{"abc", 0, chunkSize + 5, 0},
+		{"abc", 0, chunkSize + 6, chunkSize + 3},
+		{"abc", 1, chunkSize + 5, -1},
+		{"xyz", 0, 3 * chunkSize, -1},
+	} {
+		got, err := NewSearcher(r, []byte(testCase.pattern)).Backward(testCase.from, testCase.to)
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if got != testCase.expected {
+			t.Errorf("Backward(%q, %d, %d) should return %d but got %d",
+				testCase.pattern, testCase.from, testCase.to, testCase.expected, got)
+		}
+	}
+}
+
+func TestSearcherAll(t *testing.T) {
+	r := strings.NewReader(strings.Repeat("x", chunkSize-1) + "aaaaa" + strings.Repeat("x", chunkSize) + "aa")
+	var got []int64
+	if err := NewSearcher(r, []byte("aa")).All(0, 3*chunkSize, func(offset int64) bool {
+		got = append(got, offset)
+		return true
+	}); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	expected := []int64{chunkSize - 1, chunkSize + 1, 2*chunkSize + 4}
+	if len(got) != len(expected) {
+		t.Fatalf("All should find %v but got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("All should find %v but got %v", expected, got)
+		}
+	}
+
+	count, err := NewSearcher(r, []byte("aa")).Count(chunkSize, 3*chunkSize)
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Count should return %d but got %d", 3, count)
+	}
+
+	if _, err := NewSearcher(r, nil).Count(0, 3*chunkSize); err == nil {
+		t.Errorf("err should not be nil for empty pattern")
+	}
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package shell
+
+import "os/exec"
+
+func command(cmd string) *exec.Cmd {
+	return exec.Command("cmd", "/c", cmd)
+}
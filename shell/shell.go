@@ -0,0 +1,24 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Run executes the command with the shell, and returns the output. The input
+// is passed to the standard input of the command. The error contains the
+// standard error output if the command fails.
+func Run(cmd string, input io.Reader) ([]byte, error) {
+	c := command(cmd)
+	var stdout, stderr bytes.Buffer
+	c.Stdin, c.Stdout, c.Stderr = input, &stdout, &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", cmd, msg)
+		}
+		return nil, fmt.Errorf("%s: %s", cmd, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,32 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		cmd, input, expected, err string
+	}{
+		{"cat", "Hello, world!", "Hello, world!", ""},
+		{"tr a-z A-Z", "Hello, world!", "HELLO, WORLD!", ""},
+		{"printf foo", "", "foo", ""},
+		{"echo error >&2; exit 1", "", "", "echo error >&2; exit 1: error"},
+		{"exit 2", "", "", "exit 2: exit status 2"},
+	} {
+		bs, err := Run(tc.cmd, strings.NewReader(tc.input))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("Run(%q) should return error %q but got: %v", tc.cmd, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(bs) != tc.expected {
+			t.Errorf("Run(%q) should return %q but got %q", tc.cmd, tc.expected, string(bs))
+		}
+	}
+}
@@ -16,6 +16,7 @@ type State struct {
 	CompletionResults []string
 	CompletionIndex   int
 	SearchMode        rune
+	Recording         rune
 	Error             error
 	ErrorType         int
 }
@@ -23,6 +24,7 @@ type State struct {
 // WindowState holds the state of one window.
 type WindowState struct {
 	Name          string
+	Modified      bool
 	Width         int
 	Offset        int64
 	Cursor        int64
@@ -32,9 +34,34 @@ type WindowState struct {
 	Mode          mode.Mode
 	Pending       bool
 	PendingByte   byte
+	PendingText   string
+	BitColumn     int
 	VisualStart   int64
+	VisualBlock   bool
 	EditedIndices []int64
 	FocusText     bool
+	Group         int
+	Display       string
+	Encoding      string
+	Base          int64
+	OffsetFormat  string
+	Format        string
+	Pane          *Pane
+	Highlights    []Highlight
+}
+
+// Pane holds the lines shown below the bytes of the window.
+type Pane struct {
+	Title string
+	Lines []string
+	Index int
+}
+
+// Highlight holds a range of bytes to be colored.
+type Highlight struct {
+	From  int64
+	To    int64
+	Color int
 }
 
 // Message types
@@ -0,0 +1,227 @@
+package template
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/bed/mathutil"
+)
+
+const maxDepth = 32
+
+const maxFields = 100000
+
+const maxValueBytes = 16
+
+// Field is a field of the binary data parsed with a template.
+type Field struct {
+	Name     string
+	Type     string
+	Offset   int64
+	Size     int64
+	Value    string
+	Children []*Field
+}
+
+type scope struct {
+	values map[string]int64
+	parent *scope
+}
+
+func (s *scope) lookup(name string) (int64, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.values[name]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+type evaluator struct {
+	t      *Template
+	r      io.ReaderAt
+	fields int
+}
+
+// Apply parses the data at the offset with the template.
+// The returned field holds the fields parsed until an error occurs.
+func (t *Template) Apply(r io.ReaderAt, offset int64) (*Field, error) {
+	e := &evaluator{t: t, r: r}
+	children, size, err := e.evalFields(t.fields, offset, nil, 0)
+	return &Field{Offset: offset, Size: size, Children: children}, err
+}
+
+func (e *evaluator) evalFields(defs []*fieldDef, offset int64, parent *scope, depth int) ([]*Field, int64, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("too deeply nested structs")
+	}
+	s := &scope{values: make(map[string]int64), parent: parent}
+	fields := make([]*Field, 0, len(defs))
+	var size int64
+	for _, def := range defs {
+		f, err := e.evalField(def, offset+size, s, depth)
+		if f != nil {
+			fields = append(fields, f)
+			size += f.Size
+		}
+		if err != nil {
+			return fields, size, err
+		}
+	}
+	return fields, size, nil
+}
+
+func (e *evaluator) evalField(def *fieldDef, offset int64, s *scope, depth int) (*Field, error) {
+	if e.fields++; e.fields > maxFields {
+		return nil, errors.New("too many fields")
+	}
+	f := &Field{Name: def.name, Type: def.typeName(), Offset: offset}
+	count := int64(-1)
+	if def.count != "" {
+		var ok bool
+		if count, ok = parseCount(def.count); !ok {
+			if count, ok = s.lookup(def.count); !ok {
+				return nil, fmt.Errorf("%s: unknown field: %s", def.name, def.count)
+			}
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("%s: negative count: %d", def.name, count)
+		}
+	}
+	if def.typ == "char" || def.typ == "bytes" {
+		if f.Size = count; count < 0 {
+			f.Size = 1
+		}
+		bs, err := e.read(offset, f.Size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", def.name, err)
+		}
+		if def.typ == "char" {
+			f.Value = strconv.Quote(string(bs))
+		} else {
+			f.Value = fmt.Sprintf("%x", bs)
+		}
+		if f.Size > maxValueBytes {
+			f.Value += "..."
+		}
+		return f, nil
+	}
+	if count < 0 {
+		return e.evalElement(def, f, offset, s, depth, true)
+	}
+	for i := int64(0); i < count; i++ {
+		g, err := e.evalElement(def, &Field{Name: "[" + strconv.FormatInt(i, 10) + "]",
+			Type: def.elemTypeName(), Offset: offset + f.Size}, offset+f.Size, s, depth, false)
+		if g != nil {
+			f.Children = append(f.Children, g)
+			f.Size += g.Size
+		}
+		if err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (e *evaluator) evalElement(def *fieldDef, f *Field, offset int64, s *scope, depth int, store bool) (*Field, error) {
+	if size, ok := primitiveSizes[def.typ]; ok {
+		bs, err := e.read(offset, size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", def.name, err)
+		}
+		var v int64
+		f.Size = size
+		f.Value, v = formatValue(def.typ, def.order, bs)
+		if store {
+			s.values[def.name] = v
+		}
+		return f, nil
+	}
+	children, size, err := e.evalFields(e.t.structs[def.typ], offset, s, depth+1)
+	f.Children, f.Size = children, size
+	return f, err
+}
+
+func (e *evaluator) read(offset, size int64) ([]byte, error) {
+	bs := make([]byte, mathutil.MaxInt64(size, 0))
+	if size > maxValueBytes {
+		bs = bs[:maxValueBytes]
+	}
+	if n, err := e.r.ReadAt(bs, offset); n < len(bs) {
+		if err == nil || err == io.EOF {
+			err = errors.New("unexpected end of data")
+		}
+		return nil, err
+	}
+	if size > maxValueBytes {
+		if n, _ := e.r.ReadAt(make([]byte, 1), offset+size-1); n < 1 {
+			return nil, errors.New("unexpected end of data")
+		}
+	}
+	return bs, nil
+}
+
+func formatValue(typ string, order binary.ByteOrder, bs []byte) (string, int64) {
+	var u uint64
+	switch len(bs) {
+	case 1:
+		u = uint64(bs[0])
+	case 2:
+		u = uint64(order.Uint16(bs))
+	case 4:
+		u = uint64(order.Uint32(bs))
+	case 8:
+		u = order.Uint64(bs)
+	}
+	switch typ[0] {
+	case 'i':
+		shift := uint(64 - 8*len(bs))
+		v := int64(u<<shift) >> shift
+		return strconv.FormatInt(v, 10), v
+	case 'f':
+		if len(bs) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(uint32(u))), 'g', -1, 32), 0
+		}
+		return strconv.FormatFloat(math.Float64frombits(u), 'g', -1, 64), 0
+	default:
+		return fmt.Sprintf("%d (0x%x)", u, u), int64(u)
+	}
+}
+
+// Walk calls the function for the field and its descendants in order.
+func (f *Field) Walk(fn func(*Field, int)) {
+	f.walk(fn, 0)
+}
+
+func (f *Field) walk(fn func(*Field, int), depth int) {
+	fn(f, depth)
+	for _, g := range f.Children {
+		g.walk(fn, depth+1)
+	}
+}
+
+// Lookup finds the field by the path (e.g. header.entries[2].size).
+func (f *Field) Lookup(path string) *Field {
+	for _, name := range strings.Split(strings.Replace(path, "[", ".[", -1), ".") {
+		if name == "" {
+			continue
+		}
+		var g *Field
+		for _, c := range f.Children {
+			if c.Name == name {
+				g = c
+				break
+			}
+		}
+		if g == nil {
+			return nil
+		}
+		f = g
+	}
+	return f
+}
@@ -0,0 +1,187 @@
+package template
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Template describes the layout of binary data.
+type Template struct {
+	fields  []*fieldDef
+	structs map[string][]*fieldDef
+}
+
+type fieldDef struct {
+	name  string
+	typ   string
+	order binary.ByteOrder
+	count string
+	line  int
+}
+
+var primitiveSizes = map[string]int64{
+	"u8": 1, "u16": 2, "u32": 4, "u64": 8,
+	"i8": 1, "i16": 2, "i32": 4, "i64": 8,
+	"f32": 4, "f64": 8,
+	"char": 1, "bytes": 1,
+}
+
+// Parse parses a template.
+//
+//	# comment
+//	endian big
+//	struct entry {
+//	  u16le   id
+//	  u32     size
+//	  bytes[size] data
+//	}
+//	char[4]    magic
+//	u32        count
+//	entry[count] entries
+func Parse(r io.Reader) (*Template, error) {
+	t := &Template{structs: make(map[string][]*fieldDef)}
+	var order binary.ByteOrder = binary.LittleEndian
+	var structName string
+	var fields *[]*fieldDef
+	var defs []*fieldDef
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := s.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		xs := strings.Fields(text)
+		switch {
+		case len(xs) == 0:
+		case xs[0] == "endian":
+			if len(xs) != 2 || xs[1] != "little" && xs[1] != "big" {
+				return nil, fmt.Errorf("line %d: endian should be little or big", line)
+			}
+			if xs[1] == "big" {
+				order = binary.BigEndian
+			} else {
+				order = binary.LittleEndian
+			}
+		case xs[0] == "struct":
+			if fields != nil {
+				return nil, fmt.Errorf("line %d: nested struct definition", line)
+			}
+			if len(xs) != 3 || xs[2] != "{" {
+				return nil, fmt.Errorf("line %d: invalid struct definition", line)
+			}
+			if _, ok := t.structs[xs[1]]; ok {
+				return nil, fmt.Errorf("line %d: duplicate struct: %s", line, xs[1])
+			}
+			structName, fields = xs[1], new([]*fieldDef)
+		case xs[0] == "}":
+			if fields == nil || len(xs) != 1 {
+				return nil, fmt.Errorf("line %d: unexpected }", line)
+			}
+			t.structs[structName], fields = *fields, nil
+		case len(xs) == 2:
+			f, err := parseField(xs[0], xs[1], order)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			f.line = line
+			defs = append(defs, f)
+			if fields != nil {
+				*fields = append(*fields, f)
+			} else {
+				t.fields = append(t.fields, f)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: invalid field definition: %s", line, strings.TrimSpace(text))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if fields != nil {
+		return nil, fmt.Errorf("struct %s is not closed", structName)
+	}
+	if len(t.fields) == 0 {
+		return nil, fmt.Errorf("no fields defined")
+	}
+	for _, f := range defs {
+		if _, ok := primitiveSizes[f.typ]; !ok {
+			if _, ok := t.structs[f.typ]; !ok {
+				return nil, fmt.Errorf("line %d: unknown type: %s", f.line, f.typ)
+			}
+		}
+	}
+	return t, nil
+}
+
+func parseField(typ, name string, order binary.ByteOrder) (*fieldDef, error) {
+	var count string
+	var err error
+	if typ, count, err = splitCount(typ); err != nil {
+		return nil, err
+	}
+	if count == "" {
+		if name, count, err = splitCount(name); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("empty field name")
+	}
+	if len(typ) > 2 {
+		if _, ok := primitiveSizes[typ[:len(typ)-2]]; ok {
+			order, typ = byteOrder(typ[len(typ)-2:], order), typ[:len(typ)-2]
+		}
+	}
+	return &fieldDef{name: name, typ: typ, order: order, count: count}, nil
+}
+
+func splitCount(str string) (string, string, error) {
+	i := strings.IndexByte(str, '[')
+	if i < 0 {
+		return str, "", nil
+	}
+	if !strings.HasSuffix(str, "]") || i+2 >= len(str) {
+		return "", "", fmt.Errorf("invalid count: %s", str)
+	}
+	return str[:i], str[i+1 : len(str)-1], nil
+}
+
+func byteOrder(suffix string, order binary.ByteOrder) binary.ByteOrder {
+	switch suffix {
+	case "le":
+		return binary.LittleEndian
+	case "be":
+		return binary.BigEndian
+	default:
+		return order
+	}
+}
+
+func (f *fieldDef) typeName() string {
+	if f.count != "" {
+		return f.elemTypeName() + "[" + f.count + "]"
+	}
+	return f.elemTypeName()
+}
+
+func (f *fieldDef) elemTypeName() string {
+	if size, ok := primitiveSizes[f.typ]; ok && size > 1 {
+		if f.order == binary.BigEndian {
+			return f.typ + "be"
+		}
+		return f.typ + "le"
+	}
+	return f.typ
+}
+
+func parseCount(count string) (int64, bool) {
+	n, err := strconv.ParseInt(count, 0, 64)
+	return n, err == nil
+}
@@ -0,0 +1,129 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTemplate = `# test template
+endian big
+struct entry {
+  u16le   id
+  u8      size
+  bytes[size] data
+}
+char[4]      magic
+u16          count  // number of entries
+entry[count] entries
+i8           sign
+`
+
+func TestParse(t *testing.T) {
+	tmpl, err := Parse(strings.NewReader(testTemplate))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if len(tmpl.fields) != 4 {
+		t.Errorf("the number of fields should be 4 but got: %d", len(tmpl.fields))
+	}
+	if len(tmpl.structs["entry"]) != 3 {
+		t.Errorf("the number of entry fields should be 3 but got: %d", len(tmpl.structs["entry"]))
+	}
+	for i, expected := range []string{"char[4]", "u16be", "entry[count]", "i8"} {
+		if got := tmpl.fields[i].typeName(); got != expected {
+			t.Errorf("typeName() should be %q but got %q", expected, got)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, testCase := range []struct {
+		template string
+		expected string
+	}{
+		{"", "no fields defined"},
+		{"u8 x y", "line 1: invalid field definition: u8 x y"},
+		{"endian middle", "line 1: endian should be little or big"},
+		{"struct a {\nu8 x", "struct a is not closed"},
+		{"struct a {\nstruct b {", "line 2: nested struct definition"},
+		{"}", "line 1: unexpected }"},
+		{"u8 x\nfoo y", "line 2: unknown type: foo"},
+		{"u8[ x", "line 1: invalid count: u8["},
+	} {
+		_, err := Parse(strings.NewReader(testCase.template))
+		if err == nil {
+			t.Errorf("Parse(%q) should return an error", testCase.template)
+			continue
+		}
+		if err.Error() != testCase.expected {
+			t.Errorf("Parse(%q) should return error %q but got %q", testCase.template, testCase.expected, err.Error())
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tmpl, err := Parse(strings.NewReader(testTemplate))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	r := strings.NewReader("xxTEST\x00\x02\x01\x00\x02ab\x02\x00\x00\xfe")
+	f, err := tmpl.Apply(r, 2)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if f.Offset != 2 || f.Size != 15 {
+		t.Errorf("offset and size should be 2 and 15 but got %d and %d", f.Offset, f.Size)
+	}
+	for _, testCase := range []struct {
+		path         string
+		offset, size int64
+		value        string
+	}{
+		{"magic", 2, 4, `"TEST"`},
+		{"count", 6, 2, "2 (0x2)"},
+		{"entries", 8, 8, ""},
+		{"entries[0].id", 8, 2, "1 (0x1)"},
+		{"entries[0].data", 11, 2, "6162"},
+		{"entries[1]", 13, 3, ""},
+		{"entries[1].id", 13, 2, "2 (0x2)"},
+		{"entries[1].data", 16, 0, ""},
+		{"sign", 16, 1, "-2"},
+	} {
+		g := f.Lookup(testCase.path)
+		if g == nil {
+			t.Errorf("Lookup(%q) should find a field", testCase.path)
+			continue
+		}
+		if g.Offset != testCase.offset || g.Size != testCase.size || g.Value != testCase.value {
+			t.Errorf("Lookup(%q) should be (%d, %d, %q) but got (%d, %d, %q)", testCase.path,
+				testCase.offset, testCase.size, testCase.value, g.Offset, g.Size, g.Value)
+		}
+	}
+	if g := f.Lookup("entries[2]"); g != nil {
+		t.Errorf("Lookup(%q) should return nil but got %+v", "entries[2]", g)
+	}
+	var names []string
+	f.Walk(func(g *Field, depth int) {
+		names = append(names, strings.Repeat(" ", depth)+g.Name)
+	})
+	if len(names) != 13 {
+		t.Errorf("Walk should visit 13 fields but got: %q", names)
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	tmpl, err := Parse(strings.NewReader(testTemplate))
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	f, err := tmpl.Apply(strings.NewReader("TEST\x00\x03\x01\x00\x05ab"), 0)
+	if err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if expected := "data: unexpected end of data"; err.Error() != expected {
+		t.Errorf("err should be %q but got %q", expected, err.Error())
+	}
+	if g := f.Lookup("entries[0].size"); g == nil || g.Value != "5 (0x5)" {
+		t.Errorf("the fields before the error should be returned but got %+v", g)
+	}
+}
@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/itchyny/bed/charset"
+)
+
+// textCell holds the text drawn at a byte in the text area, and the character
+// which the byte belongs to.
+type textCell struct {
+	text  string // empty for the cell covered by the preceding wide character
+	r     rune
+	valid bool
+}
+
+// decodeText decodes the bytes in the view with the charset. A character is
+// drawn at the cell of its first byte, and the cells of the following bytes
+// are filled with spaces.
+func decodeText(bytes []byte, offset int64, width int, cs *charset.Charset) []textCell {
+	cells := make([]textCell, len(bytes))
+	k := int(offset - cs.Align(offset))
+	for i := 0; i < k && i < len(bytes); i++ {
+		cells[i] = textCell{text: " ", r: utf8.RuneError}
+	}
+	for k < len(bytes) {
+		r, n := cs.Decode(bytes[k:])
+		valid := r != utf8.RuneError
+		text := "."
+		if valid && unicode.IsPrint(r) {
+			text = string(r)
+		}
+		w := runewidth.RuneWidth(r)
+		if w > 1 && (n < w || (k+1)%width == 0) {
+			text = "."
+		}
+		cells[k] = textCell{text: text, r: r, valid: valid}
+		for i := 1; i < n && k+i < len(bytes); i++ {
+			cells[k+i] = textCell{text: " ", r: r, valid: valid}
+			if text != "." && i < w {
+				cells[k+i].text = ""
+			}
+		}
+		k += n
+	}
+	return cells
+}
+
+// prettyCodePoint returns the decoded character and its code point.
+func prettyCodePoint(c textCell) string {
+	if !c.valid {
+		return "invalid"
+	}
+	var str string
+	if c.r < 0x80 {
+		str = prettyRune(byte(c.r))
+	} else if unicode.IsPrint(c.r) {
+		str = string(c.r)
+	} else {
+		str = strconv.QuoteRune(c.r)
+		str = str[1 : len(str)-1]
+	}
+	return fmt.Sprintf("'%s' U+%04X", str, c.r)
+}
+
+// flattenBytes returns the bytes drawn in the window.
+func flattenBytes(bytes [][]byte, styles [][]tcell.Style) []byte {
+	var bs []byte
+	for i := range bytes {
+		for j := range bytes[i] {
+			if styles[i][j] == math.MaxUint16 {
+				return bs
+			}
+			bs = append(bs, bytes[i][j])
+		}
+	}
+	return bs
+}
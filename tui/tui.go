@@ -1,11 +1,16 @@
 package tui
 
 import (
+	"os"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell"
 	"github.com/mattn/go-runewidth"
 
+	"github.com/itchyny/bed/clipboard"
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/key"
 	"github.com/itchyny/bed/layout"
@@ -19,6 +24,8 @@ type Tui struct {
 	mode    mode.Mode
 	screen  tcell.Screen
 	waitCh  chan struct{}
+	keyGen  int
+	mu      *sync.Mutex
 }
 
 // NewTui creates a new Tui.
@@ -34,6 +41,7 @@ func (ui *Tui) Init(eventCh chan<- event.Event) (err error) {
 		return
 	}
 	ui.waitCh = make(chan struct{})
+	ui.mu = new(sync.Mutex)
 	return ui.screen.Init()
 }
 
@@ -42,6 +50,7 @@ func (ui *Tui) initForTest(eventCh chan<- event.Event, screen tcell.SimulationSc
 	ui.mode = mode.Normal
 	ui.screen = screen
 	ui.waitCh = make(chan struct{})
+	ui.mu = new(sync.Mutex)
 	return ui.screen.Init()
 }
 
@@ -51,11 +60,16 @@ func (ui *Tui) Run(kms map[mode.Mode]*key.Manager) {
 		e := ui.screen.PollEvent()
 		switch ev := e.(type) {
 		case *tcell.EventKey:
-			if e := kms[ui.mode].Press(eventToKey(ev)); e.Type != event.Nop {
-				ui.eventCh <- e
-			} else {
+			km := kms[ui.mode]
+			ui.keyGen++
+			if !ui.emitEvents(km, km.Press(eventToKey(ev))) && !km.Pending() {
 				ui.eventCh <- event.Event{Type: event.Rune, Rune: ev.Rune()}
 			}
+		case *tcell.EventInterrupt:
+			if gen, ok := ev.Data().(int); ok && gen == ui.keyGen {
+				km := kms[ui.mode]
+				ui.emitEvents(km, km.Flush())
+			}
 		case *tcell.EventResize:
 			ui.eventCh <- event.Event{Type: event.Redraw}
 		case nil:
@@ -65,6 +79,35 @@ func (ui *Tui) Run(kms map[mode.Mode]*key.Manager) {
 	}
 }
 
+// emitEvents emits the event and the following events of the keys left, and
+// reports whether any event is emitted. The held keys not matching any
+// mapping are emitted as runes. The timer is set while the keys are pending.
+func (ui *Tui) emitEvents(km *key.Manager, e event.Event) bool {
+	var emitted bool
+	for ; e.Type != event.Nop; e = km.Next() {
+		ui.emitRunes(km.Dropped())
+		ui.eventCh <- e
+		emitted = true
+	}
+	ui.emitRunes(km.Dropped())
+	if km.Pending() {
+		gen := ui.keyGen
+		time.AfterFunc(km.Timeout(), func() {
+			ui.screen.PostEvent(tcell.NewEventInterrupt(gen))
+		})
+	}
+	return emitted
+}
+
+// emitRunes emits the held keys not matching any mapping as runes.
+func (ui *Tui) emitRunes(keys []key.Key) {
+	for _, k := range keys {
+		if r, size := utf8.DecodeRuneInString(string(k)); size == len(k) {
+			ui.eventCh <- event.Event{Type: event.Rune, Rune: r}
+		}
+	}
+}
+
 // Size returns the size for the screen.
 func (ui *Tui) Size() (int, int) {
 	return ui.screen.Size()
@@ -83,6 +126,8 @@ func (ui *Tui) Redraw(s state.State) error {
 	ui.screen.Clear()
 	ui.drawWindows(s.WindowStates, s.Layout)
 	ui.drawCmdline(s)
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
 	ui.screen.Show()
 	return nil
 }
@@ -133,6 +178,8 @@ func (ui *Tui) drawCmdline(s state.State) {
 		if s.Mode == mode.Search {
 			ui.screen.ShowCursor(1+runewidth.StringWidth(string(s.Cmdline[:s.CmdlineCursor])), height-1)
 		}
+	} else if s.Recording != 0 {
+		ui.setLine(height-1, 0, "recording @"+string(s.Recording), tcell.StyleDefault)
 	}
 }
 
@@ -159,6 +206,16 @@ func (ui *Tui) drawCompletionResults(s state.State, width int, height int) {
 	}
 }
 
+// SetClipboard sets the clipboard of the terminal with the OSC 52 escape
+// sequence, which also works over SSH. The sequence is written exclusively with
+// flushing the screen, not to be mixed in the escape sequences of the screen.
+func (ui *Tui) SetClipboard(bs []byte) error {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	_, err := os.Stdout.Write(clipboard.OSC52(bs))
+	return err
+}
+
 // Close terminates the Tui.
 func (ui *Tui) Close() error {
 	ui.screen.Fini()
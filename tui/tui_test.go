@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gdamore/tcell"
 
@@ -84,6 +85,41 @@ func TestTuiRun(t *testing.T) {
 	}
 }
 
+func TestTuiRunTimeout(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	kms := mockKeyManager()
+	kms[mode.Normal].Map([]key.Key{"j", "k"}, "<Esc>", false)
+	kms[mode.Normal].Map([]key.Key{"x", "y"}, "<Esc>", false)
+	kms[mode.Normal].SetTimeout(10 * time.Millisecond)
+	go ui.Run(kms)
+
+	screen.InjectKey(tcell.KeyRune, 'j', tcell.ModNone)
+	e := <-eventCh
+	if e.Type != event.CursorDown {
+		t.Errorf("pressing j should emit event.CursorDown after timeout but got: %+v", e)
+	}
+	screen.InjectKey(tcell.KeyRune, 'j', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'k', tcell.ModNone)
+	e = <-eventCh
+	if e.Type != event.ExecuteKeysNoremap || e.Arg != "<Esc>" {
+		t.Errorf("pressing jk should emit event.ExecuteKeysNoremap but got: %+v", e)
+	}
+	screen.InjectKey(tcell.KeyRune, 'x', tcell.ModNone)
+	e = <-eventCh
+	if e.Type != event.Rune || e.Rune != 'x' {
+		t.Errorf("pressing x should emit event.Rune after timeout but got: %+v", e)
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
 func TestTuiEmpty(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -186,6 +222,373 @@ func TestTuiScrollBar(t *testing.T) {
 	}
 }
 
+func TestTuiPane(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: &state.WindowState{
+				Name:   "",
+				Width:  16,
+				Offset: 0,
+				Cursor: 0,
+				Bytes:  []byte(strings.Repeat("a", 16*(height-4))),
+				Size:   16 * (height - 4),
+				Length: int64(16 * (height - 4)),
+				Mode:   mode.Normal,
+				Format: "PNG",
+				Pane: &state.Pane{
+					Title: "pane title",
+					Lines: []string{"line 1", "line 2"},
+					Index: 1,
+				},
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	got := strings.Split(getContents(screen), "\n")
+	for i, expected := range map[int]string{
+		14: " 0000d0 | 61 61 61 61 61 61 61 61 61 61 61 61 61 61 61 61 | aaaaaaaaaaaaaaaa # ",
+		15: " pane title ",
+		16: " line 1 ",
+		17: " line 2 ",
+		18: " [No name] [PNG] : 0x61 : 'a' ",
+	} {
+		if !strings.HasPrefix(got[i], expected) {
+			t.Errorf("line %d should start with %q but got %q", i, expected, got[i])
+		}
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiHighlights(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: &state.WindowState{
+				Name:   "",
+				Width:  16,
+				Offset: 0,
+				Cursor: 0,
+				Bytes:  []byte(strings.Repeat("a", 16*(height-3))),
+				Size:   16 * (height - 3),
+				Length: int64(16 * (height - 3)),
+				Mode:   mode.Normal,
+				Highlights: []state.Highlight{
+					{From: 0, To: 2, Color: 0},
+					{From: 2, To: 3, Color: 1},
+					{From: 18, To: 20, Color: 7},
+					{From: 32, To: 40, Color: 2},
+					{From: 33, To: 36, Color: 3},
+					{From: 34, To: 35, Color: 4},
+					{From: 37, To: 38, Color: 5},
+				},
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	cells, _, _ := screen.GetContents()
+	for _, testCase := range []struct {
+		line, index int
+		expected    tcell.Color
+	}{
+		{1, 0, tcell.ColorNavy},
+		{1, 1, tcell.ColorNavy},
+		{1, 2, tcell.ColorDarkGreen},
+		{1, 3, tcell.ColorDefault},
+		{2, 1, tcell.ColorDefault},
+		{2, 2, tcell.ColorDarkGreen},
+		{2, 3, tcell.ColorDarkGreen},
+		{2, 4, tcell.ColorDefault},
+		{3, 0, tcell.ColorMaroon},
+		{3, 1, tcell.ColorPurple},
+		{3, 2, tcell.ColorTeal},
+		{3, 3, tcell.ColorPurple},
+		{3, 4, tcell.ColorMaroon},
+		{3, 5, tcell.ColorOlive},
+		{3, 6, tcell.ColorMaroon},
+		{3, 7, tcell.ColorMaroon},
+		{3, 8, tcell.ColorDefault},
+	} {
+		_, bg, _ := cells[testCase.line*width+3*testCase.index+10].Style.Decompose()
+		if bg != testCase.expected {
+			t.Errorf("background color of byte %d on line %d should be %v but got %v",
+				testCase.index, testCase.line, testCase.expected, bg)
+		}
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiGroup(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	s := state.State{
+		WindowStates: map[int]*state.WindowState{
+			0: &state.WindowState{
+				Name:   "",
+				Width:  10,
+				Offset: 0,
+				Cursor: 5,
+				Bytes:  []byte(strings.Repeat("abcdefghij", height-3)),
+				Size:   10 * (height - 3),
+				Length: int64(10 * (height - 3)),
+				Mode:   mode.Normal,
+				Group:  4,
+			},
+		},
+		Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+	}
+	if err := ui.Redraw(s); err != nil {
+		t.Errorf("ui.Redraw should return nil but got: %v", err)
+	}
+
+	got := strings.Split(getContents(screen), "\n")
+	for i, expected := range map[int]string{
+		0: "        |  0 1 2 3  4 5 6 7  8 9 | ",
+		1: " 000000 | 61626364 65666768 696a | abcdefghij # ",
+		2: " 00000a | 61626364 65666768 696a | abcdefghij # ",
+	} {
+		if !strings.HasPrefix(got[i], expected) {
+			t.Errorf("line %d should start with %q but got %q", i, expected, got[i])
+		}
+	}
+	if x, y, visible := screen.GetCursor(); x != 21 || y != 1 || !visible {
+		t.Errorf("cursor should be at (21, 1) but got (%d, %d)", x, y)
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiDisplay(t *testing.T) {
+	ui := NewTui()
+	eventCh := make(chan event.Event)
+	screen := tcell.NewSimulationScreen("")
+	if err := ui.initForTest(eventCh, screen); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(90, 20)
+	width, height := screen.Size()
+	go ui.Run(mockKeyManager())
+
+	for _, testCase := range []struct {
+		display  string
+		expected []string
+		cursor   int
+	}{
+		{"binary", []string{
+			"        |        0        1        2        3 | ",
+			" 000000 | 01000001 10000000 11111111 01000000 | A... # ",
+		}, 42},
+		{"octal", []string{
+			"        |   0   1   2   3 | ",
+			" 000000 | 101 200 377 400 | A... # ",
+		}, 23},
+		{"decimal", []string{
+			"        |   0   1   2   3 | ",
+			" 000000 |  65 128 255 400 | A... # ",
+		}, 23},
+		{"signed", []string{
+			"        |    0    1    2    3 | ",
+			" 000000 |   65 -128   -1 4000 | A... # ",
+		}, 26},
+	} {
+		s := state.State{
+			WindowStates: map[int]*state.WindowState{
+				0: &state.WindowState{
+					Name:        "",
+					Width:       4,
+					Offset:      0,
+					Cursor:      3,
+					Bytes:       []byte(strings.Repeat("A\x80\xff\x00", height-3)),
+					Size:        4 * (height - 3),
+					Length:      int64(4 * (height - 3)),
+					Mode:        mode.Insert,
+					Pending:     true,
+					PendingText: "4",
+					Display:     testCase.display,
+				},
+			},
+			Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+		}
+		if testCase.display == "binary" {
+			s.WindowStates[0].PendingText = "01000"
+		}
+		if err := ui.Redraw(s); err != nil {
+			t.Errorf("ui.Redraw should return nil but got: %v", err)
+		}
+		got := strings.Split(getContents(screen), "\n")
+		for i, expected := range testCase.expected {
+			if !strings.HasPrefix(got[i], expected) {
+				t.Errorf("line %d should start with %q but got %q", i, expected, got[i])
+			}
+		}
+		if x, _, _ := screen.GetCursor(); x != testCase.cursor {
+			t.Errorf("cursor should be at %d but got %d (%s)", testCase.cursor, x, testCase.display)
+		}
+	}
+	if err := ui.Close(); err != nil {
+		t.Errorf("ui.Close should return nil but got %v", err)
+	}
+}
+
+func TestTuiOffsetFormat(t *testing.T) {
+	for _, testCase := range []struct {
+		format   string
+		expected []string
+	}{
+		{"hex", []string{
+			"          |  0  1  2  3 | ",
+			" 08000000 | 61 62 63 64 | abcd # ",
+			" 08000004 | 61 62 63 64 | abcd # ",
+			" 134217734/134217768 : 0x08000006/0x08000028 : 15.00% ",
+		}},
+		{"dec", []string{
+			"           |  0  1  2  3 | ",
+			" 134217728 | 61 62 63 64 | abcd # ",
+			" 134217732 | 61 62 63 64 | abcd # ",
+		}},
+		{"both", []string{
+			"                    |  0  1  2  3 | ",
+			" 08000000 134217728 | 61 62 63 64 | abcd # ",
+			" 08000004 134217732 | 61 62 63 64 | abcd # ",
+		}},
+	} {
+		ui := NewTui()
+		eventCh := make(chan event.Event)
+		screen := tcell.NewSimulationScreen("")
+		if err := ui.initForTest(eventCh, screen); err != nil {
+			t.Fatal(err)
+		}
+		screen.SetSize(90, 20)
+		width, height := screen.Size()
+		go ui.Run(mockKeyManager())
+
+		s := state.State{
+			WindowStates: map[int]*state.WindowState{
+				0: &state.WindowState{
+					Name:         "",
+					Width:        4,
+					Offset:       0,
+					Cursor:       6,
+					Bytes:        []byte(strings.Repeat("abcd", height)),
+					Size:         40,
+					Length:       40,
+					Mode:         mode.Normal,
+					Base:         0x08000000,
+					OffsetFormat: testCase.format,
+				},
+			},
+			Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+		}
+		if err := ui.Redraw(s); err != nil {
+			t.Errorf("ui.Redraw should return nil but got: %v", err)
+		}
+		shouldContain(t, screen, testCase.expected)
+		if err := ui.Close(); err != nil {
+			t.Errorf("ui.Close should return nil but got %v", err)
+		}
+	}
+}
+
+func TestTuiEncoding(t *testing.T) {
+	for _, testCase := range []struct {
+		encoding string
+		bytes    string
+		cursor   int64
+		expected []string
+	}{
+		{"utf-8", "A\xe3\x81\x82\xe3\x81\x84\xff\xc3\xa9", 2, []string{
+			" 000000 | 41 e3 81 82 e3 81 84 ff c3 a9 | Aあ い .é  # ",
+			" 00000a | 41 e3 81 82 e3 81 84 ff c3 a9 | Aあ い .é  # ",
+			" 0x81 : 'あ' U+3042 ",
+		}},
+		{"utf-16le", "\x42\x30A\x00\x00\xd8\x0a\x00\x41\x00", 5, []string{
+			" 000000 | 42 30 41 00 00 d8 0a 00 41 00 | あA . . A  # ",
+			" 0xd8 : invalid ",
+		}},
+		{"shift_jis", "\x82\xa0\xb1ABCDEF\x82", 0, []string{
+			" 000000 | 82 a0 b1 41 42 43 44 45 46 82 | あｱABCDEF. # ",
+			" 0x82 : 'あ' U+3042 ",
+		}},
+		{"cp037", "\xc1\xc2\x40\x81\x25\xf0\xf1\xf2\xf3\xf4", 4, []string{
+			" 000000 | c1 c2 40 81 25 f0 f1 f2 f3 f4 | AB a.01234 # ",
+			" 0x25 : '\\n' U+000A ",
+		}},
+	} {
+		ui := NewTui()
+		eventCh := make(chan event.Event)
+		screen := tcell.NewSimulationScreen("")
+		if err := ui.initForTest(eventCh, screen); err != nil {
+			t.Fatal(err)
+		}
+		screen.SetSize(90, 20)
+		width, height := screen.Size()
+		go ui.Run(mockKeyManager())
+
+		s := state.State{
+			WindowStates: map[int]*state.WindowState{
+				0: &state.WindowState{
+					Name:     "",
+					Width:    10,
+					Offset:   0,
+					Cursor:   testCase.cursor,
+					Bytes:    []byte(strings.Repeat(testCase.bytes, height-3)),
+					Size:     10 * (height - 3),
+					Length:   int64(10 * (height - 3)),
+					Mode:     mode.Normal,
+					Encoding: testCase.encoding,
+				},
+			},
+			Layout: layout.NewLayout(0).Resize(0, 0, width, height-1),
+		}
+		if err := ui.Redraw(s); err != nil {
+			t.Errorf("ui.Redraw should return nil but got: %v", err)
+		}
+		shouldContain(t, screen, testCase.expected)
+		if err := ui.Close(); err != nil {
+			t.Errorf("ui.Close should return nil but got %v", err)
+		}
+	}
+}
+
 func TestTuiHorizontalSplit(t *testing.T) {
 	ui := NewTui()
 	eventCh := make(chan event.Event)
@@ -7,12 +7,19 @@ import (
 	"strings"
 
 	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
 
+	"github.com/itchyny/bed/charset"
 	"github.com/itchyny/bed/mathutil"
 	"github.com/itchyny/bed/mode"
 	"github.com/itchyny/bed/state"
 )
 
+var highlightColors = []tcell.Color{
+	tcell.ColorNavy, tcell.ColorDarkGreen, tcell.ColorMaroon,
+	tcell.ColorPurple, tcell.ColorTeal, tcell.ColorOlive,
+}
+
 type tuiWindow struct {
 	region region
 	screen tcell.Screen
@@ -27,63 +34,121 @@ func (ui *tuiWindow) setCursor(line int, offset int) {
 }
 
 func (ui *tuiWindow) offsetStyleWidth(s *state.WindowState) int {
-	threshold := int64(0xfffff)
+	hexWidth, decWidth := offsetWidths(s)
+	switch s.OffsetFormat {
+	case "dec":
+		return decWidth
+	case "both":
+		return hexWidth + 1 + decWidth
+	default:
+		return hexWidth
+	}
+}
+
+// offsetWidths returns the widths of the hexadecimal and decimal addresses,
+// which are the offsets shifted by the base.
+func offsetWidths(s *state.WindowState) (int, int) {
+	last := s.Base + s.Length
+	hexWidth, threshold := 16, int64(0xfffff)
 	for i := 0; i < 10; i++ {
-		if s.Length <= threshold {
-			return 6 + i
+		if last <= threshold {
+			hexWidth = 6 + i
+			break
 		}
 		threshold = (threshold << 4) | 0x0f
 	}
-	return 16
+	return hexWidth, mathutil.MaxInt(len(strconv.FormatInt(last, 10)), 7)
+}
+
+func formatOffset(s *state.WindowState, offset int64) string {
+	hexWidth, decWidth := offsetWidths(s)
+	switch s.OffsetFormat {
+	case "dec":
+		return fmt.Sprintf("%0*d", decWidth, s.Base+offset)
+	case "both":
+		return fmt.Sprintf("%0*x %0*d", hexWidth, s.Base+offset, decWidth, s.Base+offset)
+	default:
+		return fmt.Sprintf("%0*x", hexWidth, s.Base+offset)
+	}
 }
 
 func (ui *tuiWindow) drawWindow(s *state.WindowState, active bool) {
 	height, width := ui.region.height-2, s.Width
+	if s.Pane != nil {
+		height -= len(s.Pane.Lines) + 1
+	}
 	bytes, styles := ui.bytesArray(height, width, s)
+	var texts []textCell
+	if cs := charset.Lookup(s.Encoding); cs != nil && cs.Name != "ascii" {
+		texts = decodeText(flattenBytes(bytes, styles), s.Offset, width, cs)
+	}
+	group, cellWidth := mathutil.MaxInt(s.Group, 1), displayCellWidth(s.Display)
+	hexWidth := hexWindowWidth(width, group, cellWidth)
 	cursorPos := int(s.Cursor - s.Offset)
 	cursorLine := cursorPos / width
 	offsetStyleWidth := ui.offsetStyleWidth(s)
-	offsetStyle := " %0" + strconv.Itoa(offsetStyleWidth) + "x"
 	d := ui.getTextDrawer()
 	for i := 0; i < height; i++ {
 		d.setTop(i + 1).setLeft(0).setOffset(0)
-		d.setString(fmt.Sprintf(offsetStyle, s.Offset+int64(i*width)), tcell.StyleDefault.Bold(i == cursorLine))
+		d.setString(" "+formatOffset(s, s.Offset+int64(i*width)), tcell.StyleDefault.Bold(i == cursorLine))
 		d.setLeft(offsetStyleWidth + 3)
 		for j := 0; j < width; j++ {
+			x := hexOffset(j, group, cellWidth)
+			if j%group == 0 {
+				d.setOffset(x-1).setString(" ", tcell.StyleDefault)
+			}
 			if styles[i][j] == math.MaxUint16 {
-				d.setOffset(3*j).setString("   ", tcell.StyleDefault)
-				d.setOffset(3*width+j+3).setString(" ", tcell.StyleDefault)
+				d.setOffset(x).setString(strings.Repeat(" ", cellWidth), tcell.StyleDefault)
+				d.setOffset(hexWidth+j+3).setString(" ", tcell.StyleDefault)
 			} else {
-				d.setOffset(3*j).setString(" ", tcell.StyleDefault)
 				if i*width+j == cursorPos {
 					styles[i][j] = styles[i][j].Reverse(active && !s.FocusText).Bold(
 						!active || s.FocusText).Underline(!active || s.FocusText)
 				}
-				d.setOffset(3*j+1).setString(fmt.Sprintf("%02x", bytes[i][j]), styles[i][j])
+				if s.Pending && i*width+j == cursorPos && s.PendingText != "" {
+					d.setOffset(x).setString(s.PendingText+strings.Repeat(
+						"0", mathutil.MaxInt(cellWidth-len(s.PendingText), 0)), styles[i][j])
+				} else {
+					d.setOffset(x).setString(formatByte(bytes[i][j], s.Display), styles[i][j])
+				}
 				if i*width+j == cursorPos {
 					styles[i][j] = styles[i][j].Reverse(active && s.FocusText).Bold(
 						!active || !s.FocusText).Underline(!active || !s.FocusText)
 				}
-				d.setOffset(3*width+j+3).setString(string(prettyByte(bytes[i][j])), styles[i][j])
+				text := string(prettyByte(bytes[i][j]))
+				if texts != nil {
+					text = texts[i*width+j].text
+				}
+				if text != "" {
+					d.setOffset(hexWidth+j+3).setString(text, styles[i][j])
+				}
 			}
 		}
 		d.setOffset(-2).setString(" | ", tcell.StyleDefault)
-		d.setOffset(3*width).setString(" | ", tcell.StyleDefault)
-		d.setOffset(4*width+3).setString(" ", tcell.StyleDefault)
+		d.setOffset(hexWidth).setString(" | ", tcell.StyleDefault)
+		d.setOffset(hexWidth+width+3).setString(" ", tcell.StyleDefault)
 	}
 	i := int(s.Cursor % int64(width))
 	if active {
 		if s.FocusText {
-			ui.setCursor(cursorLine+1, 3*width+i+6+offsetStyleWidth)
+			ui.setCursor(cursorLine+1, hexWidth+i+6+offsetStyleWidth)
 		} else if s.Pending {
-			ui.setCursor(cursorLine+1, 3*i+5+offsetStyleWidth)
+			ui.setCursor(cursorLine+1, hexOffset(i, group, cellWidth)+
+				mathutil.MinInt(len(s.PendingText), cellWidth-1)+3+offsetStyleWidth)
+		} else if s.Mode == mode.Normal || s.Mode == mode.Replace {
+			ui.setCursor(cursorLine+1, hexOffset(i, group, cellWidth)+s.BitColumn+3+offsetStyleWidth)
 		} else {
-			ui.setCursor(cursorLine+1, 3*i+4+offsetStyleWidth)
+			ui.setCursor(cursorLine+1, hexOffset(i, group, cellWidth)+3+offsetStyleWidth)
 		}
 	}
 	ui.drawHeader(s, offsetStyleWidth)
-	ui.drawScrollBar(s, height, 4*width+7+offsetStyleWidth)
-	ui.drawFooter(s, offsetStyleWidth)
+	ui.drawScrollBar(s, height, hexWidth+width+7+offsetStyleWidth)
+	ui.drawPane(s.Pane, height+1)
+	var text *textCell
+	if texts != nil && cursorPos < len(texts) {
+		text = &texts[cursorPos]
+	}
+	ui.drawFooter(s, text)
 }
 
 func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]byte, [][]tcell.Style) {
@@ -91,7 +156,8 @@ func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]by
 	if height <= 0 {
 		return nil, nil
 	}
-	eis := s.EditedIndices
+	eis, hs := s.EditedIndices, s.Highlights
+	var active []state.Highlight
 	bytes := make([][]byte, height)
 	styles := make([][]tcell.Style, height)
 	color := tcell.ColorLightSeaGreen
@@ -117,9 +183,13 @@ func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]by
 			} else if 0 < len(eis) && eis[1] <= pos {
 				eis = eis[2:]
 			}
-			if s.VisualStart >= 0 && s.Cursor < s.Length &&
-				(s.VisualStart <= pos && pos <= s.Cursor ||
-					s.Cursor <= pos && pos <= s.VisualStart) {
+			for 0 < len(hs) && hs[0].From <= pos {
+				active, hs = append(active, hs[0]), hs[1:]
+			}
+			if h := innermostHighlight(&active, pos); h != nil {
+				styles[i][j] = styles[i][j].Background(highlightColors[h.Color%len(highlightColors)])
+			}
+			if s.VisualStart >= 0 && s.Cursor < s.Length && inVisual(s, pos) {
 				styles[i][j] = styles[i][j].Underline(true)
 			}
 			k++
@@ -128,17 +198,94 @@ func (ui *tuiWindow) bytesArray(height, width int, s *state.WindowState) ([][]by
 	return bytes, styles
 }
 
+// innermostHighlight removes the highlights which end before the position,
+// and returns the innermost one of the highlights covering the position. The
+// highlight which starts later, or ends earlier if they start together, is
+// considered inner, so that the nested annotations are colored.
+func innermostHighlight(active *[]state.Highlight, pos int64) *state.Highlight {
+	hs := (*active)[:0]
+	for _, h := range *active {
+		if pos < h.To {
+			hs = append(hs, h)
+		}
+	}
+	*active = hs
+	var inner *state.Highlight
+	for i, h := range hs {
+		if inner == nil || h.From > inner.From || h.From == inner.From && h.To < inner.To {
+			inner = &hs[i]
+		}
+	}
+	return inner
+}
+
+// inVisual reports whether the position is in the visual selection, which
+// is a rectangular region of the rows in blockwise visual mode.
+func inVisual(s *state.WindowState, pos int64) bool {
+	if s.VisualBlock {
+		width := int64(s.Width)
+		row, col := pos/width, pos%width
+		return mathutil.MinInt64(s.VisualStart/width, s.Cursor/width) <= row &&
+			row <= mathutil.MaxInt64(s.VisualStart/width, s.Cursor/width) &&
+			mathutil.MinInt64(s.VisualStart%width, s.Cursor%width) <= col &&
+			col <= mathutil.MaxInt64(s.VisualStart%width, s.Cursor%width)
+	}
+	return s.VisualStart <= pos && pos <= s.Cursor || s.Cursor <= pos && pos <= s.VisualStart
+}
+
+// hexOffset returns the offset of the i-th byte in the hex area.
+// A space is put before each group of bytes.
+func hexOffset(i, group, cellWidth int) int {
+	return cellWidth*i + i/group + 1
+}
+
+// hexWindowWidth returns the width of the hex area of the bytes in a line.
+func hexWindowWidth(width, group, cellWidth int) int {
+	return hexOffset(width-1, group, cellWidth) + cellWidth
+}
+
+func displayCellWidth(display string) int {
+	switch display {
+	case "binary":
+		return 8
+	case "octal", "decimal":
+		return 3
+	case "signed":
+		return 4
+	default:
+		return 2
+	}
+}
+
+func formatByte(b byte, display string) string {
+	switch display {
+	case "binary":
+		return fmt.Sprintf("%08b", b)
+	case "octal":
+		return fmt.Sprintf("%03o", b)
+	case "decimal":
+		return fmt.Sprintf("%3d", b)
+	case "signed":
+		return fmt.Sprintf("%4d", int8(b))
+	default:
+		return fmt.Sprintf("%02x", b)
+	}
+}
+
 func (ui *tuiWindow) drawHeader(s *state.WindowState, offsetStyleWidth int) {
 	style := tcell.StyleDefault.Underline(true)
+	group, cellWidth := mathutil.MaxInt(s.Group, 1), displayCellWidth(s.Display)
+	hexWidth := hexWindowWidth(s.Width, group, cellWidth)
 	d := ui.getTextDrawer()
-	d.setString(strings.Repeat(" ", 4*s.Width+8+offsetStyleWidth), style)
+	d.setString(strings.Repeat(" ", hexWidth+s.Width+8+offsetStyleWidth), style)
 	d.setLeft(offsetStyleWidth)
 	cursor := int(s.Cursor % int64(s.Width))
 	for i := 0; i < s.Width; i++ {
-		d.setOffset(3*i+4).setString(fmt.Sprintf("%2x", i), style.Bold(cursor == i))
+		d.setOffset(hexOffset(i, group, cellWidth)+3).setString(
+			fmt.Sprintf("%*x", cellWidth, i), style.Bold(cursor == i))
 	}
 	d.setOffset(2).setString("|", style)
-	d.setOffset(3*s.Width+4).setString("|", style)
+	d.setOffset(hexWidth+4).setString("|", style)
 }
 
 func (ui *tuiWindow) drawScrollBar(s *state.WindowState, height int, left int) {
@@ -162,20 +309,43 @@ func (ui *tuiWindow) drawScrollBar(s *state.WindowState, height int, left int) {
 	}
 }
 
-func (ui *tuiWindow) drawFooter(s *state.WindowState, offsetStyleWidth int) {
-	offsetStyle := "0x%0" + strconv.Itoa(offsetStyleWidth) + "x"
+func (ui *tuiWindow) drawPane(p *state.Pane, top int) {
+	if p == nil {
+		return
+	}
+	d := ui.getTextDrawer().setTop(top)
+	style := tcell.StyleDefault.Underline(true)
+	d.setString(" "+p.Title+strings.Repeat(" ", ui.region.width), style)
+	for i, line := range p.Lines {
+		d.setTop(top+i+1).setString(" "+line, tcell.StyleDefault.Reverse(i == p.Index))
+	}
+}
+
+func (ui *tuiWindow) drawFooter(s *state.WindowState, text *textCell) {
+	hexWidth, _ := offsetWidths(s)
+	offsetStyle := "0x%0" + strconv.Itoa(hexWidth) + "x"
 	j := int(s.Cursor - s.Offset)
 	name := s.Name
 	if name == "" {
 		name = "[No name]"
 	}
-	left := fmt.Sprintf(" %s%s : 0x%02x : '%s'",
-		prettyMode(s.Mode), name, s.Bytes[j], prettyRune(s.Bytes[j]))
+	if s.Modified {
+		name += " [+]"
+	}
+	if s.Format != "" {
+		name += " [" + s.Format + "]"
+	}
+	char := "'" + prettyRune(s.Bytes[j]) + "'"
+	if text != nil {
+		char = prettyCodePoint(*text)
+	}
+	left := fmt.Sprintf(" %s%s : 0x%02x : %s",
+		prettyMode(s.Mode, s.VisualBlock), name, s.Bytes[j], char)
 	right := fmt.Sprintf("%d/%d : "+offsetStyle+"/"+offsetStyle+" : %.2f%% ",
-		s.Cursor, s.Length, s.Cursor, s.Length,
+		s.Base+s.Cursor, s.Base+s.Length, s.Base+s.Cursor, s.Base+s.Length,
 		float64(s.Cursor*100)/float64(mathutil.MaxInt64(s.Length, 1)))
 	line := left + strings.Repeat(
-		" ", mathutil.MaxInt(2, ui.region.width-len(left)-len(right)),
+		" ", mathutil.MaxInt(2, ui.region.width-runewidth.StringWidth(left)-len(right)),
 	) + right
 	ui.getTextDrawer().setTop(ui.region.height-1).setString(line, tcell.StyleDefault.Reverse(true))
 }
@@ -216,13 +386,16 @@ func prettyRune(b byte) string {
 	}
 }
 
-func prettyMode(m mode.Mode) string {
+func prettyMode(m mode.Mode, block bool) string {
 	switch m {
 	case mode.Insert:
 		return "[INSERT] "
 	case mode.Replace:
 		return "[REPLACE] "
 	case mode.Visual:
+		if block {
+			return "[VISUAL BLOCK] "
+		}
 		return "[VISUAL] "
 	default:
 		return ""
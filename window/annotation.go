@@ -0,0 +1,302 @@
+package window
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/state"
+)
+
+// annotationColors are the names of the highlight colors in the order of
+// the color palette of the user interface.
+var annotationColors = []string{"blue", "green", "red", "purple", "teal", "olive"}
+
+type annotation struct {
+	From  int64  `json:"from"`
+	To    int64  `json:"to"`
+	Color string `json:"color"`
+	Note  string `json:"note"`
+}
+
+type annotationFile struct {
+	Annotations []annotation `json:"annotations"`
+}
+
+// annotationPath returns the path of the sidecar file next to the file.
+func annotationPath(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".bed.json")
+}
+
+func loadAnnotations(filename string) ([]annotation, error) {
+	bs, err := ioutil.ReadFile(annotationPath(filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var f annotationFile
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath.Base(annotationPath(filename)), err)
+	}
+	sortAnnotations(f.Annotations)
+	return f.Annotations, nil
+}
+
+func saveAnnotations(filename string, annotations []annotation) error {
+	path := annotationPath(filename)
+	if len(annotations) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	bs, err := json.MarshalIndent(annotationFile{annotations}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpf, err := os.OpenFile(
+		path+"-"+strconv.FormatUint(rand.Uint64(), 16),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644,
+	)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpf.Name())
+	_, err = tmpf.Write(append(bs, '\n'))
+	tmpf.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpf.Name(), path)
+}
+
+func sortAnnotations(annotations []annotation) {
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].From < annotations[j].From
+	})
+}
+
+// parseAnnotation parses the argument of :annotate; an optional color name
+// followed by the note, which can be quoted.
+func parseAnnotation(arg string) (string, string, error) {
+	var color string
+	if xs := strings.Fields(arg); len(xs) > 1 {
+		for _, c := range annotationColors {
+			if xs[0] == c {
+				color, arg = c, strings.TrimSpace(arg[len(c):])
+				break
+			}
+		}
+	}
+	if strings.HasPrefix(arg, `"`) {
+		note, err := strconv.Unquote(arg)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid note: %s", arg)
+		}
+		arg = note
+	}
+	if arg == "" {
+		return "", "", errors.New("empty note")
+	}
+	return color, arg, nil
+}
+
+func colorIndex(color string) int {
+	for i, c := range annotationColors {
+		if c == color {
+			return i
+		}
+	}
+	return 0
+}
+
+func (w *window) setMark(name rune) {
+	if w.marks == nil {
+		w.marks = make(map[rune]int64)
+	}
+	w.marks[name] = w.cursor
+}
+
+func (w *window) jumpMark(name rune) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gotoMark(name)
+}
+
+func (w *window) hasMark(name rune) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.marks[name]
+	return ok
+}
+
+func (w *window) gotoMark(name rune) error {
+	offset, ok := w.marks[name]
+	if !ok {
+		return fmt.Errorf("mark not set: %c", name)
+	}
+	w.cursorGotoOffset(offset)
+	return nil
+}
+
+func (w *window) annotate(r *event.Range, color, note string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.filename == "" {
+		return errors.New("no file name")
+	}
+	from, to := w.cursor, w.cursor
+	if r != nil {
+		var err error
+		if from, to, err = w.rangeToOffsets(r); err != nil {
+			return err
+		}
+	}
+	if color == "" {
+		color = annotationColors[len(w.annotations)%len(annotationColors)]
+	}
+	annotations := append(append([]annotation{}, w.annotations...),
+		annotation{From: from, To: to, Color: color, Note: note})
+	sortAnnotations(annotations)
+	if err := saveAnnotations(w.filename, annotations); err != nil {
+		return err
+	}
+	w.annotations = annotations
+	return nil
+}
+
+// unannotate removes the annotations overlapping with the range.
+func (w *window) unannotate(r *event.Range) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	from, to := w.cursor, w.cursor
+	if r != nil {
+		var err error
+		if from, to, err = w.rangeToOffsets(r); err != nil {
+			return 0, err
+		}
+	}
+	var annotations []annotation
+	for _, a := range w.annotations {
+		if a.To < from || to < a.From {
+			annotations = append(annotations, a)
+		}
+	}
+	count := len(w.annotations) - len(annotations)
+	if count == 0 {
+		return 0, errors.New("no annotations found")
+	}
+	if err := saveAnnotations(w.filename, annotations); err != nil {
+		return 0, err
+	}
+	w.annotations = annotations
+	return count, nil
+}
+
+func (w *window) toggleAnnotations() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.annotations) == 0 {
+		return errors.New("no annotations")
+	}
+	w.togglePane(paneAnnotations)
+	return nil
+}
+
+func (w *window) jumpAnnotation(count int64, forward bool) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.annotations) == 0 {
+		return "", errors.New("no annotations")
+	}
+	index := -1
+	for n := mathutil.MaxInt64(count, 1); n > 0; n-- {
+		i := w.nextAnnotation(index, forward)
+		if i < 0 {
+			break
+		}
+		index = i
+	}
+	if index < 0 {
+		if forward {
+			return "", errors.New("no more annotations")
+		}
+		return "", errors.New("no previous annotations")
+	}
+	a := w.annotations[index]
+	w.cursorGotoOffset(a.From)
+	return a.Note, nil
+}
+
+// nextAnnotation returns the index of the annotation next to the one at the
+// index, or to the cursor if the index is negative.
+func (w *window) nextAnnotation(index int, forward bool) int {
+	if index >= 0 {
+		if forward {
+			index++
+		} else {
+			index--
+		}
+		if 0 <= index && index < len(w.annotations) {
+			return index
+		}
+		return -1
+	}
+	if forward {
+		for i, a := range w.annotations {
+			if a.From > w.cursor {
+				return i
+			}
+		}
+		return -1
+	}
+	for i := len(w.annotations) - 1; i >= 0; i-- {
+		if w.annotations[i].From < w.cursor {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *window) annotationPane(height int) *state.Pane {
+	index := -1
+	for i, a := range w.annotations {
+		if a.From <= w.cursor && w.cursor <= a.To {
+			index = i
+		}
+	}
+	start, end := paneRange(index, len(w.annotations), height)
+	lines := make([]string, 0, end-start)
+	for _, a := range w.annotations[start:end] {
+		lines = append(lines, fmt.Sprintf("%08x-%08x %-6s %s", a.From, a.To, a.Color, a.Note))
+	}
+	if index >= 0 {
+		index -= start
+	}
+	return &state.Pane{Title: "annotations", Lines: lines, Index: index}
+}
+
+// annotationHighlights returns the ranges of the annotations in the view.
+func (w *window) annotationHighlights() []state.Highlight {
+	var highlights []state.Highlight
+	from, to := w.offset, w.offset+w.height*w.width
+	for _, a := range w.annotations {
+		if from <= a.To && a.From < to {
+			highlights = append(highlights, state.Highlight{
+				From: a.From, To: a.To + 1, Color: colorIndex(a.Color),
+			})
+		}
+	}
+	return highlights
+}
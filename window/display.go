@@ -0,0 +1,170 @@
+package window
+
+import (
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/mode"
+)
+
+// display is the way to show and input the bytes.
+type display struct {
+	name   string
+	base   int
+	digits int
+	signed bool
+}
+
+var displays = []*display{
+	{name: "hex", base: 16, digits: 2},
+	{name: "binary", base: 2, digits: 8},
+	{name: "octal", base: 8, digits: 3},
+	{name: "decimal", base: 10, digits: 3},
+	{name: "signed", base: 10, digits: 3, signed: true},
+}
+
+func lookupDisplay(name string) *display {
+	for _, d := range displays {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+const digits = "0123456789abcdef"
+
+func (d *display) digitValue(ch rune) (byte, bool) {
+	var v byte
+	switch {
+	case '0' <= ch && ch <= '9':
+		v = byte(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		v = byte(ch-'a') + 0x0a
+	default:
+		return 0, false
+	}
+	return v, int(v) < d.base
+}
+
+// limit returns the maximum absolute value of a byte.
+func (d *display) limit(negative bool) int {
+	if !d.signed {
+		return 0xff
+	} else if negative {
+		return 0x80
+	}
+	return 0x7f
+}
+
+// cellWidth returns the number of columns to show a byte.
+func (d *display) cellWidth() int {
+	if d.signed {
+		return d.digits + 1
+	}
+	return d.digits
+}
+
+func (w *window) cellWidth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.display.cellWidth()
+}
+
+// insertByte inputs a digit in the base of the display. The byte is
+// inserted when all the digits are typed or the next digit overflows.
+func (w *window) insertByte(m mode.Mode, b byte) {
+	if !w.pending {
+		w.pendingText, w.pendingNum = "", 0
+	}
+	negative := len(w.pendingText) > 0 && w.pendingText[0] == '-'
+	base, limit := w.display.base, w.display.limit(negative)
+	value := w.pendingNum*base + int(b)
+	if value > limit {
+		return
+	}
+	w.pendingText += string(digits[b])
+	w.pendingNum = value
+	n := len(w.pendingText)
+	if negative {
+		n--
+	}
+	if n == w.display.digits || value*base > limit {
+		if negative {
+			value = -value
+		}
+		w.putByte(m, byte(value))
+		return
+	}
+	w.pending = true
+	for ; n < w.display.digits; n++ {
+		value *= base
+	}
+	w.pendingByte = byte(value)
+}
+
+// bitwise reports whether the cursor points to a bit of the byte, which is
+// the case in the binary display. The bit column 0 is the most significant bit.
+func (w *window) bitwise() bool {
+	return w.display.base == 2 && !w.focusText
+}
+
+func (w *window) bitCursor() int {
+	if !w.bitwise() {
+		return 0
+	}
+	return w.bitColumn
+}
+
+// replaceBit replaces the bit at the cursor in the binary display. The cursor
+// moves to the next bit in replace mode, and to the next byte after the last
+// bit of the byte.
+func (w *window) replaceBit(m mode.Mode, set bool) {
+	_, bytes, err := w.readBytes(w.cursor, 1)
+	if err != nil {
+		return
+	}
+	mask := byte(0x80) >> uint(w.bitColumn)
+	b := bytes[0] &^ mask
+	if set {
+		b |= mask
+	}
+	if w.replaceByte || w.bitColumn == 7 {
+		if !w.replaceByte {
+			w.bitColumn = 0
+		}
+		w.putByte(m, b)
+		return
+	}
+	w.replace(w.cursor, b)
+	if w.length == 0 {
+		w.length++
+	}
+	w.pending = false
+	w.bitColumn++
+}
+
+// toggleBit toggles the bits from the cursor in the binary display, and moves
+// the cursor to the next bit.
+func (w *window) toggleBit(count int64) {
+	if !w.bitwise() || w.length == 0 {
+		return
+	}
+	bits := w.cursor*8 + int64(w.bitColumn)
+	end := bits + mathutil.MinInt64(mathutil.MaxInt64(count, 1), w.length*8-bits)
+	for bits < end {
+		offset := bits / 8
+		_, bytes, err := w.readBytes(offset, 1)
+		if err != nil {
+			return
+		}
+		b := bytes[0]
+		for ; bits < end && bits/8 == offset; bits++ {
+			b ^= byte(0x80) >> uint(bits%8)
+		}
+		w.replace(offset, b)
+	}
+	bits = mathutil.MinInt64(end, w.length*8-1)
+	w.cursor, w.bitColumn = bits/8, int(bits%8)
+	if w.cursor >= w.offset+w.height*w.width {
+		w.offset = (w.cursor - w.height*w.width + w.width) / w.width * w.width
+	}
+}
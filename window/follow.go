@@ -0,0 +1,65 @@
+package window
+
+import (
+	"time"
+
+	"github.com/itchyny/bed/mathutil"
+)
+
+// followInterval is the interval to check the length of the file.
+var followInterval = 500 * time.Millisecond
+
+// setFollow starts or stops watching the file to grow. The caller should hold
+// the lock of the window.
+func (w *window) setFollow(follow bool) {
+	if follow && w.followStop == nil {
+		w.followStop = make(chan struct{})
+		w.followWg.Add(1)
+		go w.follow(w.followStop)
+	} else if !follow && w.followStop != nil {
+		close(w.followStop)
+		w.followStop = nil
+	}
+}
+
+// follow checks the length of the file periodically like tail -f, until the
+// stop channel is closed.
+func (w *window) follow(stopCh <-chan struct{}) {
+	defer w.followWg.Done()
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			if w.grow() {
+				select {
+				case w.redrawCh <- struct{}{}:
+				case <-stopCh:
+					return
+				}
+			}
+		}
+	}
+}
+
+// grow updates the length when the file grows, and reports whether the length
+// is updated. The cursor is kept at the end if it is on the last byte.
+func (w *window) grow() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	length, err := w.buffer.Len()
+	if err != nil || length <= w.length {
+		return false
+	}
+	if w.cursor >= w.length-1 && w.visualStart < 0 {
+		w.cursor = length - 1
+		if w.width > 0 && w.cursor >= w.offset+w.height*w.width {
+			w.offset = mathutil.MaxInt64(
+				(w.cursor-w.height*w.width+w.width)/w.width*w.width, 0)
+		}
+	}
+	w.length = length
+	return true
+}
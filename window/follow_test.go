@@ -0,0 +1,83 @@
+package window
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWindowFollow(t *testing.T) {
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = 10 * time.Millisecond
+	f, err := ioutil.TempFile("", "bed-test-window-follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString("0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	redrawCh := make(chan struct{})
+	window, err := newWindow(f, f.Name(), "test", redrawCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(4, 2)
+	window.cursor = 9
+	window.setOption("follow", true)
+	if window.getOption("follow") != true {
+		t.Errorf("follow option should be true")
+	}
+
+	if _, err := f.WriteString("abcdefghij"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-redrawCh:
+	case <-time.After(time.Second):
+		t.Fatalf("window should redraw when the file grows")
+	}
+	s, err := window.state()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Length != 20 {
+		t.Errorf("s.Length should be %d but got %d", 20, s.Length)
+	}
+	if s.Cursor != 19 {
+		t.Errorf("s.Cursor should be %d but got %d", 19, s.Cursor)
+	}
+	if s.Offset != 12 {
+		t.Errorf("s.Offset should be %d but got %d", 12, s.Offset)
+	}
+
+	window.mu.Lock()
+	window.cursor = 0
+	window.mu.Unlock()
+	if _, err := f.WriteString("klmno"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-redrawCh:
+	case <-time.After(time.Second):
+		t.Fatalf("window should redraw when the file grows")
+	}
+	s, err = window.state()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Length != 25 {
+		t.Errorf("s.Length should be %d but got %d", 25, s.Length)
+	}
+	if s.Cursor != 0 {
+		t.Errorf("s.Cursor should be %d but got %d", 0, s.Cursor)
+	}
+
+	window.setOption("follow", false)
+	if window.getOption("follow") != false {
+		t.Errorf("follow option should be false")
+	}
+	window.close()
+}
@@ -0,0 +1,67 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/bed/format"
+	"github.com/itchyny/bed/state"
+)
+
+const formatStyle = "%-24s %-10s %s"
+
+func (w *window) toggleFormat() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.format == nil {
+		return errors.New("unknown file format")
+	}
+	w.togglePane(paneFormat)
+	return nil
+}
+
+func (w *window) gotoEntry(kind, name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.format == nil {
+		return errors.New("unknown file format")
+	}
+	if kind != w.format.Kind {
+		return fmt.Errorf("%s has no %ss", w.format.Name, kind)
+	}
+	e, ok := w.format.Find(name, w.cursor)
+	if !ok {
+		return fmt.Errorf("%s not found: %s", kind, name)
+	}
+	w.cursorGotoOffset(e.Offset)
+	return nil
+}
+
+// recognizeFormat detects the format again after the buffer is written,
+// because the entries of the format refer to the offsets in the file.
+func (w *window) recognizeFormat() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.format = format.Recognize(w.buffer, w.length); w.format == nil && w.pane == paneFormat {
+		w.pane = paneNone
+	}
+}
+
+func (w *window) formatPane(height int) *state.Pane {
+	f := w.format
+	index := f.Lookup(w.cursor)
+	start, end := paneRange(index, len(f.Entries), height)
+	lines := make([]string, 0, end-start)
+	for _, e := range f.Entries[start:end] {
+		lines = append(lines, strings.TrimRight(fmt.Sprintf(formatStyle,
+			e.Name, fmt.Sprintf("%08x", e.Offset), fmt.Sprintf("%x", e.Size)), " "))
+	}
+	if index >= 0 {
+		index -= start
+	}
+	return &state.Pane{
+		Title: fmt.Sprintf(formatStyle, f.Name+" "+f.Kind+"s", "offset", "size"),
+		Lines: lines, Index: index,
+	}
+}
@@ -0,0 +1,181 @@
+package window
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const inspectorSize = 16
+
+const inspectorStyle = "%-10s %-24s %s"
+
+var inspectorTitle = fmt.Sprintf(inspectorStyle, "inspector", "little endian", "big endian")
+
+// filetimeEpoch is the difference between the epoch of FILETIME (1601-01-01)
+// and the unix epoch in seconds.
+const filetimeEpoch = 11644473600
+
+type inspectorRow struct {
+	name string
+	size int
+	le   func([]byte) string
+	be   func([]byte) string
+}
+
+var inspectorRows = []inspectorRow{
+	{"int8", 1, func(bs []byte) string { return strconv.Itoa(int(int8(bs[0]))) }, nil},
+	{"uint8", 1, func(bs []byte) string { return strconv.Itoa(int(bs[0])) }, nil},
+	{"int16", 2,
+		func(bs []byte) string { return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(bs)))) },
+		func(bs []byte) string { return strconv.Itoa(int(int16(binary.BigEndian.Uint16(bs)))) }},
+	{"uint16", 2,
+		func(bs []byte) string { return strconv.Itoa(int(binary.LittleEndian.Uint16(bs))) },
+		func(bs []byte) string { return strconv.Itoa(int(binary.BigEndian.Uint16(bs))) }},
+	{"int32", 4,
+		func(bs []byte) string { return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(bs)))) },
+		func(bs []byte) string { return strconv.Itoa(int(int32(binary.BigEndian.Uint32(bs)))) }},
+	{"uint32", 4,
+		func(bs []byte) string { return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(bs)), 10) },
+		func(bs []byte) string { return strconv.FormatUint(uint64(binary.BigEndian.Uint32(bs)), 10) }},
+	{"int64", 8,
+		func(bs []byte) string { return strconv.FormatInt(int64(binary.LittleEndian.Uint64(bs)), 10) },
+		func(bs []byte) string { return strconv.FormatInt(int64(binary.BigEndian.Uint64(bs)), 10) }},
+	{"uint64", 8,
+		func(bs []byte) string { return strconv.FormatUint(binary.LittleEndian.Uint64(bs), 10) },
+		func(bs []byte) string { return strconv.FormatUint(binary.BigEndian.Uint64(bs), 10) }},
+	{"float32", 4,
+		func(bs []byte) string {
+			return formatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(bs))), 32)
+		},
+		func(bs []byte) string {
+			return formatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(bs))), 32)
+		}},
+	{"float64", 8,
+		func(bs []byte) string { return formatFloat(math.Float64frombits(binary.LittleEndian.Uint64(bs)), 64) },
+		func(bs []byte) string { return formatFloat(math.Float64frombits(binary.BigEndian.Uint64(bs)), 64) }},
+	{"unix time", 4,
+		func(bs []byte) string { return formatTime(time.Unix(int64(binary.LittleEndian.Uint32(bs)), 0)) },
+		func(bs []byte) string { return formatTime(time.Unix(int64(binary.BigEndian.Uint32(bs)), 0)) }},
+	{"FILETIME", 8,
+		func(bs []byte) string { return formatFiletime(binary.LittleEndian.Uint64(bs)) },
+		func(bs []byte) string { return formatFiletime(binary.BigEndian.Uint64(bs)) }},
+	{"uleb128", 1, formatULEB128, nil},
+	{"sleb128", 1, formatSLEB128, nil},
+	{"guid", 16, formatGUID, nil},
+	{"uuid", 16, formatUUID, nil},
+	{"utf-8", 1, formatUTF8, nil},
+	{"utf-16", 2,
+		func(bs []byte) string { return formatUTF16(bs, binary.LittleEndian) },
+		func(bs []byte) string { return formatUTF16(bs, binary.BigEndian) }},
+}
+
+// inspect decodes the bytes in various types for the data inspector.
+func inspect(bs []byte) []string {
+	if len(bs) > inspectorSize {
+		bs = bs[:inspectorSize]
+	}
+	lines := make([]string, len(inspectorRows))
+	for i, row := range inspectorRows {
+		var le, be string
+		if len(bs) >= row.size {
+			le = row.le(bs)
+			if row.be != nil {
+				be = row.be(bs)
+			}
+		}
+		lines[i] = strings.TrimRight(fmt.Sprintf(inspectorStyle, row.name, le, be), " ")
+	}
+	return lines
+}
+
+func formatFloat(f float64, bitSize int) string {
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+func formatFiletime(v uint64) string {
+	return formatTime(time.Unix(int64(v/1e7)-filetimeEpoch, int64(v%1e7)*100))
+}
+
+func decodeLEB128(bs []byte) (uint64, uint, int) {
+	var v uint64
+	var shift uint
+	for i, b := range bs {
+		if shift < 64 {
+			v |= uint64(b&0x7f) << shift
+		}
+		shift += 7
+		if b&0x80 == 0 {
+			return v, shift, i + 1
+		}
+	}
+	return 0, 0, 0
+}
+
+func formatULEB128(bs []byte) string {
+	v, _, n := decodeLEB128(bs)
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d (%d bytes)", v, n)
+}
+
+func formatSLEB128(bs []byte) string {
+	v, shift, n := decodeLEB128(bs)
+	if n == 0 {
+		return "-"
+	}
+	if shift < 64 && bs[n-1]&0x40 != 0 {
+		v |= ^uint64(0) << shift
+	}
+	return fmt.Sprintf("%d (%d bytes)", int64(v), n)
+}
+
+func formatGUID(bs []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(bs), binary.LittleEndian.Uint16(bs[4:]),
+		binary.LittleEndian.Uint16(bs[6:]), bs[8:10], bs[10:16])
+}
+
+func formatUUID(bs []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bs[:4], bs[4:6], bs[6:8], bs[8:10], bs[10:16])
+}
+
+func formatCodePoint(r rune, n int) string {
+	if !unicode.IsPrint(r) {
+		return fmt.Sprintf("U+%04X (%d bytes)", r, n)
+	}
+	return fmt.Sprintf("U+%04X '%c' (%d bytes)", r, r, n)
+}
+
+func formatUTF8(bs []byte) string {
+	r, n := utf8.DecodeRune(bs)
+	if r == utf8.RuneError && n <= 1 {
+		return "-"
+	}
+	return formatCodePoint(r, n)
+}
+
+func formatUTF16(bs []byte, order binary.ByteOrder) string {
+	r := rune(order.Uint16(bs))
+	if utf16.IsSurrogate(r) {
+		if len(bs) < 4 {
+			return "-"
+		}
+		if r = utf16.DecodeRune(r, rune(order.Uint16(bs[2:]))); r == utf8.RuneError {
+			return "-"
+		}
+		return formatCodePoint(r, 4)
+	}
+	return formatCodePoint(r, 2)
+}
@@ -0,0 +1,59 @@
+package window
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	bs := []byte("\xe3\x81\x82\x00\x00\x80\x3f\x00\x01\x02\x03\x04\x05\x06\x07\x08")
+	expected := []string{
+		"int8       -29",
+		"uint8      227",
+		"int16      -32285                   -7295",
+		"uint16     33251                    58241",
+		"int32      8552931                  -478051840",
+		"uint32     8552931                  3816915456",
+		"int64      17873661029679587        -2053217018584219904",
+		"uint64     17873661029679587        16393527055125331712",
+		"float32    1.1985209e-38            -4.777995e+21",
+		"float64    1.7522456652647048e-307  -2.1143624521123193e+171",
+		"unix time  1970-04-09 23:48:51      2090-12-14 06:17:36",
+		"FILETIME   1657-08-22 02:35:02      53550-01-08 15:31:52",
+		"uleb128    32995 (4 bytes)",
+		"sleb128    32995 (4 bytes)",
+		"guid       008281e3-8000-003f-0102-030405060708",
+		"uuid       e3818200-0080-3f00-0102-030405060708",
+		"utf-8      U+3042 'あ' (3 bytes)",
+		"utf-16     U+81E3 '臣' (2 bytes)     U+E381 (2 bytes)",
+	}
+	got := inspect(bs)
+	if !reflect.DeepEqual(got, expected) {
+		for i := range got {
+			if i >= len(expected) || got[i] != expected[i] {
+				t.Errorf("inspect should return %q but got %q", expected[i], got[i])
+			}
+		}
+	}
+
+	got = inspect([]byte("\xff\x7f"))
+	for i, line := range []string{
+		"int16      32767                    -129",
+		"int32",
+		"sleb128    -1 (2 bytes)",
+		"utf-8      -",
+	} {
+		if !contains(got, line) {
+			t.Errorf("inspect should contain %q (%d) but got %q", line, i, got)
+		}
+	}
+}
+
+func contains(xs []string, x string) bool {
+	for _, y := range xs {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
@@ -4,17 +4,26 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/mitchellh/go-homedir"
 
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/format"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/option"
+	"github.com/itchyny/bed/register"
+	"github.com/itchyny/bed/searcher"
+	"github.com/itchyny/bed/shell"
 	"github.com/itchyny/bed/state"
 )
 
@@ -30,12 +39,18 @@ type Manager struct {
 	files           []file
 	eventCh         chan<- event.Event
 	redrawCh        chan<- struct{}
+	options         option.Values
+	optionsMu       *sync.RWMutex
+	registers       *register.Registers
+	done            chan struct{}
+	scanWg          *sync.WaitGroup
 }
 
 type file struct {
-	name string
-	file *os.File
-	perm os.FileMode
+	name   string
+	file   *os.File
+	perm   os.FileMode
+	window *window
 }
 
 // NewManager creates a new Manager.
@@ -47,16 +62,29 @@ func NewManager() *Manager {
 func (m *Manager) Init(eventCh chan<- event.Event, redrawCh chan<- struct{}) {
 	m.eventCh, m.redrawCh = eventCh, redrawCh
 	m.mu = new(sync.Mutex)
+	m.options, m.optionsMu = make(option.Values), new(sync.RWMutex)
+	m.registers = register.New()
+	m.done, m.scanWg = make(chan struct{}), new(sync.WaitGroup)
 }
 
 // Open a new window.
 func (m *Manager) Open(filename string) error {
+	return m.openWindow(filename, false)
+}
+
+// Recover opens a new window, and recovers the changes from the swap file.
+func (m *Manager) Recover(filename string) error {
+	return m.openWindow(filename, true)
+}
+
+func (m *Manager) openWindow(filename string, recovery bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	window, err := m.open(filename)
+	window, err := m.open(filename, recovery)
 	if err != nil {
 		return err
 	}
+	m.setOptions(window)
 	go window.run()
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
@@ -64,12 +92,16 @@ func (m *Manager) Open(filename string) error {
 	return nil
 }
 
-func (m *Manager) open(filename string) (*window, error) {
+func (m *Manager) open(filename string, recovery bool) (*window, error) {
 	if filename == "" {
+		if recovery {
+			return nil, errors.New("no file name")
+		}
 		window, err := newWindow(bytes.NewReader(nil), "", "", m.redrawCh)
 		if err != nil {
 			return nil, err
 		}
+		window.registers = m.registers
 		return window, nil
 	}
 	name, err := homedir.Expand(filename)
@@ -86,6 +118,10 @@ func (m *Manager) open(filename string) (*window, error) {
 		if err != nil {
 			return nil, err
 		}
+		window.registers = m.registers
+		if err := m.openSwap(window, recovery); err != nil {
+			return nil, err
+		}
 		return window, nil
 	}
 	info, err := os.Stat(filename)
@@ -95,11 +131,23 @@ func (m *Manager) open(filename string) (*window, error) {
 	if info.IsDir() {
 		return nil, fmt.Errorf("%s is a directory", filename)
 	}
-	m.files = append(m.files, file{name: filename, file: f, perm: info.Mode().Perm()})
 	window, err := newWindow(f, filename, filepath.Base(filename), m.redrawCh)
 	if err != nil {
+		f.Close()
 		return nil, err
 	}
+	m.files = append(m.files, file{name: filename, file: f, perm: info.Mode().Perm(), window: window})
+	window.registers = m.registers
+	window.fileInfo = info
+	if err := m.openSwap(window, recovery); err != nil {
+		return nil, err
+	}
+	window.format = format.Recognize(f, info.Size())
+	if window.annotations, err = loadAnnotations(filename); err != nil {
+		go func() {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}()
+	}
 	return window, nil
 }
 
@@ -145,49 +193,49 @@ func (m *Manager) Emit(e event.Event) {
 		} else if err := m.wincmd(e.Arg); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowDown:
 		if err := m.wincmd("j"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowUp:
 		if err := m.wincmd("k"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowLeft:
 		if err := m.wincmd("h"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowRight:
 		if err := m.wincmd("l"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowTopLeft:
 		if err := m.wincmd("t"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowBottomRight:
 		if err := m.wincmd("b"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.FocusWindowPrevious:
 		if err := m.wincmd("p"); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		} else {
-			m.eventCh <- event.Event{Type: event.Redraw}
+			m.eventCh <- m.checkTime(false)
 		}
 	case event.MoveWindowTop:
 		if err := m.wincmd("K"); err != nil {
@@ -213,6 +261,82 @@ func (m *Manager) Emit(e event.Event) {
 		} else {
 			m.eventCh <- event.Event{Type: event.Redraw}
 		}
+	case event.CountMatches:
+		if err := m.countMatches(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.FindAll:
+		if err := m.findAll(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.NextMatch:
+		if err := m.jumpMatch(e, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.PreviousMatch:
+		if err := m.jumpMatch(e, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.ApplyTemplate:
+		if err := m.applyTemplate(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.GotoField:
+		if err := m.gotoField(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.ToggleFormat:
+		if len(e.Arg) > 0 {
+			m.eventCh <- event.Event{Type: event.Error, Error: fmt.Errorf("too many arguments for %s", e.CmdName)}
+		} else if err := m.windows[m.windowIndex].toggleFormat(); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.GotoEntry:
+		if err := m.gotoEntry(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.JumpMark:
+		if err := m.windows[m.windowIndex].jumpMark(e.Rune); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Annotate:
+		if err := m.annotate(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.Unannotate:
+		if err := m.unannotate(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.ToggleAnnotations:
+		if err := m.windows[m.windowIndex].toggleAnnotations(); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+	case event.NextAnnotation:
+		if err := m.jumpAnnotation(e, true); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.PreviousAnnotation:
+		if err := m.jumpAnnotation(e, false); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Set, event.SetLocal:
+		if err := m.set(e, e.Type == event.SetLocal); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
 	case event.Quit:
 		if err := m.quit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
@@ -225,24 +349,73 @@ func (m *Manager) Emit(e event.Event) {
 		if err := m.writeQuit(e); err != nil {
 			m.eventCh <- event.Event{Type: event.Error, Error: err}
 		}
+	case event.SaveAs:
+		if err := m.saveAs(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Update:
+		if err := m.update(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.WriteAll, event.WriteAllQuit:
+		if err := m.writeAll(e, e.Type == event.WriteAllQuit); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.CheckTime:
+		if len(e.Arg) > 0 {
+			m.eventCh <- event.Event{Type: event.Error, Error: fmt.Errorf("too many arguments for %s", e.CmdName)}
+		} else if e.CmdName != "" {
+			m.eventCh <- m.checkTime(true)
+		} else if e := m.checkTime(false); e.Type != event.Redraw {
+			// the periodic check does not block the editor, because the user
+			// interface can send the events to the channel meanwhile
+			m.async(func() event.Event { return e })
+		}
+	case event.Filter:
+		if err := m.filter(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
+	case event.Read:
+		if err := m.read(e); err != nil {
+			m.eventCh <- event.Event{Type: event.Error, Error: err}
+		}
 	default:
 		m.windows[m.windowIndex].eventCh <- e
 	}
 }
 
+// Operate applies the pending operator to the range of the motion, and returns
+// the mode after the operator. The change operator starts insert mode only if
+// the motion succeeds.
+func (m *Manager) Operate(e event.Event) mode.Mode {
+	window := m.windows[m.windowIndex]
+	if e.Type == event.JumpMark && !window.hasMark(e.Rune) {
+		m.eventCh <- event.Event{Type: event.Error, Error: fmt.Errorf("mark not set: %c", e.Rune)}
+	}
+	window.eventCh <- e
+	return <-window.operated
+}
+
 func (m *Manager) edit(e event.Event) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	var name string
 	if len(e.Arg) == 0 {
+		if e.Bang {
+			return m.reload(m.windowIndex)
+		}
+		if m.windows[m.windowIndex].modified() {
+			return errors.New("no write since last change (add ! to override)")
+		}
 		name = m.windows[m.windowIndex].filename
 	} else {
 		name = e.Arg
 	}
-	window, err := m.open(name)
+	window, err := m.open(name, false)
 	if err != nil {
 		return err
 	}
+	m.setOptions(window)
 	go window.run()
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
@@ -253,10 +426,11 @@ func (m *Manager) edit(e event.Event) error {
 func (m *Manager) newWindow(e event.Event, vertical bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	window, err := m.open(e.Arg)
+	window, err := m.open(e.Arg, false)
 	if err != nil {
 		return err
 	}
+	m.setOptions(window)
 	go window.run()
 	m.windows = append(m.windows, window)
 	m.windowIndex, m.prevWindowIndex = len(m.windows)-1, m.windowIndex
@@ -352,6 +526,258 @@ func (m *Manager) move(modifier func(layout.Window, layout.Layout) layout.Layout
 		activeWindow.Index).Resize(0, 0, m.width, m.height)
 }
 
+func (m *Manager) countMatches(e event.Event) error {
+	pattern, err := parsePattern(e.Arg)
+	if err != nil {
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	buffer, from, to, err := window.snapshot(e.Range)
+	if err != nil {
+		return err
+	}
+	m.async(func() event.Event {
+		r := &findReader{buffer, func() error {
+			select {
+			case <-window.done:
+				return errFindStopped
+			default:
+				return nil
+			}
+		}}
+		count, err := searcher.NewSearcher(r, pattern).Count(from, to)
+		if err == errFindStopped {
+			return event.Event{}
+		} else if err != nil {
+			return event.Event{Type: event.Error, Error: err}
+		}
+		return event.Event{Type: event.Info, Error: fmt.Errorf("%d (0x%x) matches", count, count)}
+	})
+	return nil
+}
+
+// maxMatches is the maximum number of the matches stored by :findall, so that
+// a frequent pattern in a large file does not run out of memory.
+var maxMatches = 1 << 20
+
+func (m *Manager) findAll(e event.Event) error {
+	pattern, err := parsePattern(e.Arg)
+	if err != nil {
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	buffer, from, to, stop, err := window.startFind(e.Range)
+	if err != nil {
+		return err
+	}
+	m.async(func() event.Event {
+		var matches []int64
+		var truncated bool
+		r := &findReader{buffer, func() error { return window.findInterrupted(stop) }}
+		err := searcher.NewSearcher(r, pattern).All(from, to, func(offset int64) bool {
+			if len(matches) >= maxMatches {
+				truncated = true
+				return false
+			}
+			matches = append(matches, offset)
+			return true
+		})
+		if err == nil {
+			err = window.setMatches(matches, stop)
+		}
+		if err == errFindStopped {
+			return event.Event{}
+		} else if err != nil {
+			return event.Event{Type: event.Error, Error: err}
+		}
+		msg := fmt.Sprintf("%d (0x%x) matches found", len(matches), len(matches))
+		if truncated {
+			msg += " (the rest are not stored)"
+		}
+		return event.Event{Type: event.Info, Error: errors.New(msg)}
+	})
+	return nil
+}
+
+// async runs the function in a goroutine, and sends the resulting event unless
+// it is Nop. The event is not sent after the manager is closed.
+func (m *Manager) async(f func() event.Event) {
+	m.scanWg.Add(1)
+	go func() {
+		defer m.scanWg.Done()
+		if e := f(); e.Type != event.Nop {
+			select {
+			case m.eventCh <- e:
+			case <-m.done:
+			}
+		}
+	}()
+}
+
+// findReader checks if the scan is interrupted on each read, so that the long
+// scan for a large file stops on the changes or another scan.
+type findReader struct {
+	r     io.ReaderAt
+	check func() error
+}
+
+func (r *findReader) ReadAt(p []byte, off int64) (int, error) {
+	if err := r.check(); err != nil {
+		return 0, err
+	}
+	return r.r.ReadAt(p, off)
+}
+
+func (m *Manager) jumpMatch(e event.Event, forward bool) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	index, count, err := m.windows[m.windowIndex].jumpMatch(e.Count, forward)
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("(%d of %d)", index+1, count)}
+	return nil
+}
+
+func (m *Manager) applyTemplate(e event.Event) error {
+	window := m.windows[m.windowIndex]
+	if len(e.Arg) == 0 {
+		return window.toggleTemplate()
+	}
+	name, pos := parseTemplateArg(e.Arg)
+	t, err := readTemplate(name)
+	if err != nil {
+		return err
+	}
+	return window.applyTemplate(t, filepath.Base(name), pos)
+}
+
+func (m *Manager) gotoField(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	return m.windows[m.windowIndex].gotoField(e.Arg)
+}
+
+func (m *Manager) gotoEntry(e event.Event) error {
+	xs := strings.SplitN(e.Arg, " ", 2)
+	if len(xs) < 2 || strings.TrimSpace(xs[1]) == "" {
+		return fmt.Errorf("%s requires section, chunk or entry and its name", e.CmdName)
+	}
+	return m.windows[m.windowIndex].gotoEntry(xs[0], strings.TrimSpace(xs[1]))
+}
+
+func (m *Manager) annotate(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	color, note, err := parseAnnotation(e.Arg)
+	if err != nil {
+		return err
+	}
+	return m.windows[m.windowIndex].annotate(e.Range, color, note)
+}
+
+func (m *Manager) unannotate(e event.Event) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	count, err := m.windows[m.windowIndex].unannotate(e.Range)
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("%d annotations removed", count)}
+	return nil
+}
+
+func (m *Manager) jumpAnnotation(e event.Event, forward bool) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	note, err := m.windows[m.windowIndex].jumpAnnotation(e.Count, forward)
+	if err != nil {
+		return err
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: errors.New(note)}
+	return nil
+}
+
+// set sets the options. The window-local options are also set globally to
+// be used for new windows, unless local is true.
+func (m *Manager) set(e event.Event, local bool) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	settings, err := option.Parse(e.Arg)
+	if err != nil {
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	var values []string
+	for _, s := range settings {
+		o := s.Option
+		value := m.options.Get(o.Name)
+		if o.Scope == option.Local {
+			value = window.getOption(o.Name)
+		}
+		switch s.Op {
+		case option.Query:
+			values = append(values, o.Format(value))
+			continue
+		case option.Toggle:
+			s.Value = value != true
+		}
+		if o.Scope == option.Local {
+			window.setOption(o.Name, s.Value)
+			if local {
+				continue
+			}
+		}
+		m.optionsMu.Lock()
+		m.options[o.Name] = s.Value
+		m.optionsMu.Unlock()
+	}
+	if len(values) > 0 {
+		m.eventCh <- event.Event{Type: event.Info, Error: errors.New(strings.Join(values, "  "))}
+	} else {
+		m.eventCh <- event.Event{Type: event.Redraw}
+	}
+	return nil
+}
+
+// Option returns the global value of the option. The options are also read
+// by the clipboard while the windows handle the events.
+func (m *Manager) Option(name string) interface{} {
+	m.optionsMu.RLock()
+	defer m.optionsMu.RUnlock()
+	return m.options.Get(name)
+}
+
+// Register returns the bytes of the register.
+func (m *Manager) Register(name rune) ([]byte, error) {
+	return m.registers.Get(name)
+}
+
+// SetRegister stores the bytes to the register.
+func (m *Manager) SetRegister(name rune, bs []byte) error {
+	return m.registers.Set(name, bs)
+}
+
+// SetClipboard sets the system clipboard of the registers.
+func (m *Manager) SetClipboard(clipboard register.Clipboard) {
+	m.registers.SetClipboard(clipboard)
+}
+
+// setOptions sets the global values of the window-local options to the window.
+func (m *Manager) setOptions(window *window) {
+	for name, value := range m.options {
+		if o := option.Lookup(name); o != nil && o.Scope == option.Local {
+			window.setOption(name, value)
+		}
+	}
+}
+
 func (m *Manager) quit(e event.Event) error {
 	if len(e.Arg) > 0 {
 		return fmt.Errorf("too many arguments for %s", e.CmdName)
@@ -370,10 +796,26 @@ func (m *Manager) quit(e event.Event) error {
 }
 
 func (m *Manager) write(e event.Event) error {
-	if e.Range != nil && e.Arg == "" {
+	name, appending, offset, err := parseWriteArg(e.Arg)
+	if err != nil {
+		return err
+	}
+	if appending || offset >= 0 {
+		filename, n, err := m.patchFile(e.Range, name, appending, offset)
+		if err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("%s: %d (0x%x) bytes appended", filename, n, n)
+		if !appending {
+			msg = fmt.Sprintf("%s: %d (0x%x) bytes written at 0x%x", filename, n, n, offset)
+		}
+		m.eventCh <- event.Event{Type: event.Info, Error: errors.New(msg)}
+		return nil
+	}
+	if e.Range != nil && name == "" {
 		return fmt.Errorf("cannot overwrite partially with %s", e.CmdName)
 	}
-	filename, n, err := m.writeFile(e.Range, e.Arg)
+	filename, n, err := m.writeFile(m.windows[m.windowIndex], e.Range, name, e.Bang)
 	if err != nil {
 		return err
 	}
@@ -381,6 +823,29 @@ func (m *Manager) write(e event.Event) error {
 	return nil
 }
 
+// parseWriteArg parses the argument of :write; >> file to append to the file,
+// or ++offset=N file to write at the offset of the file. The offset is -1 if
+// not specified.
+func parseWriteArg(arg string) (string, bool, int64, error) {
+	if strings.HasPrefix(arg, ">>") {
+		return strings.TrimSpace(arg[2:]), true, -1, nil
+	}
+	if strings.HasPrefix(arg, "++offset=") {
+		opt := arg
+		if i := strings.IndexFunc(arg, unicode.IsSpace); i > 0 {
+			opt, arg = arg[:i], strings.TrimSpace(arg[i:])
+		} else {
+			arg = ""
+		}
+		offset, err := strconv.ParseInt(opt[len("++offset="):], 0, 64)
+		if err != nil || offset < 0 {
+			return "", false, 0, fmt.Errorf("invalid offset: %s", opt[len("++offset="):])
+		}
+		return arg, false, offset, nil
+	}
+	return arg, false, -1, nil
+}
+
 func (m *Manager) writeQuit(e event.Event) error {
 	if len(e.Arg) > 0 {
 		return fmt.Errorf("too many arguments for %s", e.CmdName)
@@ -388,13 +853,224 @@ func (m *Manager) writeQuit(e event.Event) error {
 	if e.Range != nil {
 		return fmt.Errorf("range not allowed for %s", e.CmdName)
 	}
-	if _, _, err := m.writeFile(nil, ""); err != nil {
+	if _, _, err := m.writeFile(m.windows[m.windowIndex], nil, "", e.Bang); err != nil {
 		return err
 	}
 	m.eventCh <- event.Event{Type: event.Quit}
 	return nil
 }
 
+// saveAs writes the buffer to the file, and renames the buffer to the file.
+func (m *Manager) saveAs(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	if e.Range != nil {
+		return fmt.Errorf("range not allowed for %s", e.CmdName)
+	}
+	name, err := homedir.Expand(e.Arg)
+	if err != nil {
+		return err
+	}
+	window := m.windows[m.windowIndex]
+	if _, err := os.Stat(name); err == nil && !e.Bang && name != window.filename {
+		return fmt.Errorf("%s: file exists (add ! to override)", name)
+	}
+	filename, n, err := m.writeFile(window, nil, name, e.Bang)
+	if err != nil {
+		return err
+	}
+	if err := window.rename(filename); err != nil {
+		return err
+	}
+	window.fileInfo, _ = os.Stat(filename)
+	window.warned = false
+	window.setSaved()
+	window.recognizeFormat()
+	m.rebase(window, filename)
+	m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("%s: %d (0x%x) bytes written", filename, n, n)}
+	return nil
+}
+
+// update writes the buffer only when it is modified.
+func (m *Manager) update(e event.Event) error {
+	window := m.windows[m.windowIndex]
+	if !window.modified() {
+		m.eventCh <- event.Event{Type: event.Redraw}
+		return nil
+	}
+	return m.write(e)
+}
+
+// writeAll writes all the modified buffers, and quits the editor on :xall.
+func (m *Manager) writeAll(e event.Event, quit bool) error {
+	if len(e.Arg) > 0 {
+		return fmt.Errorf("too many arguments for %s", e.CmdName)
+	}
+	if e.Range != nil {
+		return fmt.Errorf("range not allowed for %s", e.CmdName)
+	}
+	var count int
+	for _, window := range m.windows {
+		if !window.modified() {
+			continue
+		}
+		if window.filename == "" {
+			return errors.New("no file name")
+		}
+		if _, _, err := m.writeFile(window, nil, "", e.Bang); err != nil {
+			return err
+		}
+		count++
+	}
+	if quit {
+		m.eventCh <- event.Event{Type: event.QuitAll}
+	} else {
+		m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf("%d buffers written", count)}
+	}
+	return nil
+}
+
+// filter pipes the bytes in the range to the command, and replaces them with
+// the output of the command. The command is just executed without the range.
+// The buffer is not changed if the command fails.
+func (m *Manager) filter(e event.Event) error {
+	if len(e.Arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	window := m.windows[m.windowIndex]
+	if e.Range == nil {
+		output, err := shell.Run(e.Arg, nil)
+		if err != nil {
+			return err
+		}
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			m.eventCh <- event.Event{Type: event.Info, Error: errors.New(msg)}
+		} else {
+			m.eventCh <- event.Event{Type: event.Redraw}
+		}
+		return nil
+	}
+	buffer, from, to, err := window.snapshot(e.Range)
+	if err != nil {
+		return err
+	}
+	output, err := shell.Run(e.Arg, io.NewSectionReader(buffer, from, to-from))
+	if err != nil {
+		return err
+	}
+	window.replaceBytes(from, to, output)
+	m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf(
+		"%d (0x%x) bytes filtered to %d (0x%x) bytes", to-from, to-from, len(output), len(output))}
+	return nil
+}
+
+// read inserts the contents of the file (:read file), or the output of the
+// command (:read !cmd), at the offset or the cursor. The contents overwrite
+// the bytes with ++replace. The file is read lazily, and kept open.
+func (m *Manager) read(e event.Event) error {
+	arg, replace := e.Arg, false
+	if strings.HasPrefix(arg, "++replace") &&
+		(len(arg) == len("++replace") || unicode.IsSpace(rune(arg[len("++replace")]))) {
+		arg, replace = strings.TrimSpace(arg[len("++replace"):]), true
+	}
+	if len(arg) == 0 {
+		return fmt.Errorf("an argument is required for %s", e.CmdName)
+	}
+	var name string
+	var r readAtSeeker
+	var size int64
+	if arg[0] == '!' {
+		cmd := strings.TrimSpace(arg[1:])
+		if cmd == "" {
+			return fmt.Errorf("an argument is required for %s", e.CmdName)
+		}
+		output, err := shell.Run(cmd, nil)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(output), int64(len(output))
+	} else {
+		var err error
+		if name, err = homedir.Expand(arg); err != nil {
+			return err
+		}
+		// the absolute path is recorded in the swap file
+		path, err := filepath.Abs(name)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if info.IsDir() {
+			f.Close()
+			return fmt.Errorf("%s is a directory", name)
+		}
+		m.files = append(m.files, file{name: name, file: f, perm: info.Mode().Perm(),
+			window: m.windows[m.windowIndex]})
+		r, size = f, info.Size()
+	}
+	n, err := m.windows[m.windowIndex].readFrom(e.Range, r, size, replace)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("%d (0x%x) bytes read", n, n)
+	if replace {
+		msg = fmt.Sprintf("%d (0x%x) bytes replaced", n, n)
+	}
+	if name != "" {
+		msg = name + ": " + msg
+	}
+	m.eventCh <- event.Event{Type: event.Info, Error: errors.New(msg)}
+	return nil
+}
+
+// openSwap sets the swap file of the window, and recovers the buffer from it.
+// The existing swap file is not overwritten unless it is recovered, because it
+// may have the changes lost by a crash or made by another editor.
+func (m *Manager) openSwap(window *window, recovery bool) error {
+	window.messageCh = m.eventCh
+	name := swapPath(window.filename)
+	if _, err := os.Stat(name); err != nil {
+		if recovery {
+			return fmt.Errorf("no swap file found for %s", window.filename)
+		}
+		window.swapName = name
+		return nil
+	}
+	if !recovery {
+		go func() {
+			m.eventCh <- event.Event{Type: event.Error, Error: fmt.Errorf(
+				"%s: swap file exists (recover with bed -r, or remove it)", filepath.Base(name))}
+		}()
+		return nil
+	}
+	window.swapName = name
+	files, err := window.recoverSwap()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		var perm os.FileMode = 0644
+		if info, err := f.Stat(); err == nil {
+			perm = info.Mode().Perm()
+		}
+		m.files = append(m.files, file{name: f.Name(), file: f, perm: perm, window: window})
+	}
+	go func() {
+		m.eventCh <- event.Event{Type: event.Info, Error: fmt.Errorf(
+			"%s: recovered from the swap file", window.name)}
+	}()
+	return nil
+}
+
 // State returns the state of the windows.
 func (m *Manager) State() (map[int]*state.WindowState, layout.Layout, int, error) {
 	m.mu.Lock()
@@ -403,7 +1079,7 @@ func (m *Manager) State() (map[int]*state.WindowState, layout.Layout, int, error
 	states := make(map[int]*state.WindowState, len(m.windows))
 	for i, window := range m.windows {
 		if l, ok := layouts[i]; ok {
-			window.setSize(hexWindowWidth(l.Width()), mathutil.MaxInt(l.Height()-2, 1))
+			window.setSize(hexWindowWidth(l.Width(), window.cellWidth()), mathutil.MaxInt(l.Height()-2, 1))
 			var err error
 			if states[i], err = window.state(); err != nil {
 				return nil, m.layout, 0, err
@@ -413,7 +1089,10 @@ func (m *Manager) State() (map[int]*state.WindowState, layout.Layout, int, error
 	return states, m.layout, m.windowIndex, nil
 }
 
-func hexWindowWidth(width int) int {
+func hexWindowWidth(width, cellWidth int) int {
+	// each byte takes cellWidth+2 columns (a space and the text column),
+	// so scale the width to the one of the hex display
+	width = (width-18)*4/(cellWidth+2) + 18
 	if width > 146 {
 		return 32
 	} else if width > 114 {
@@ -428,8 +1107,7 @@ func hexWindowWidth(width int) int {
 	return 4
 }
 
-func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error) {
-	window := m.windows[m.windowIndex]
+func (m *Manager) writeFile(window *window, r *event.Range, name string, force bool) (string, int64, error) {
 	if name == "" {
 		name = window.filename
 	}
@@ -444,6 +1122,9 @@ func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error)
 		window.filename = name
 		window.name = filepath.Base(name)
 	}
+	if _, changed := fileChanged(window); changed && !force && name == window.filename {
+		return name, 0, fmt.Errorf("%s: file changed since reading it (add ! to override)", name)
+	}
 	tmpf, err := os.OpenFile(
 		name+"-"+strconv.FormatUint(rand.Uint64(), 16),
 		os.O_RDWR|os.O_CREATE|os.O_EXCL, m.filePerm(name),
@@ -457,7 +1138,238 @@ func (m *Manager) writeFile(r *event.Range, name string) (string, int64, error)
 	if err != nil {
 		return name, 0, err
 	}
-	return name, n, os.Rename(tmpf.Name(), name)
+	if err = os.Rename(tmpf.Name(), name); err != nil {
+		return name, 0, err
+	}
+	if name == window.filename {
+		window.fileInfo, _ = os.Stat(name)
+		window.warned = false
+		if r == nil {
+			window.setSaved()
+			window.recognizeFormat()
+			m.rebase(window, name)
+		}
+	}
+	return name, n, nil
+}
+
+// checkTime checks whether the files of the windows are changed outside of
+// the editor, and reloads the unmodified windows if autoread is set. Each
+// change is warned once unless checked explicitly with :checktime. It returns
+// the event to report the changes, or to redraw the screen.
+func (m *Manager) checkTime(explicit bool) event.Event {
+	m.mu.Lock()
+	autoread := m.Option("autoread") == true
+	layouts := m.layout.Collect()
+	var changed, reloaded []string
+	for i, window := range m.windows {
+		if _, ok := layouts[i]; !ok {
+			continue
+		}
+		info, ok := fileChanged(window)
+		if !ok || window.warned && !explicit {
+			continue
+		}
+		if autoread && info != nil && !window.modified() {
+			if err := m.reload(i); err == nil {
+				reloaded = append(reloaded, window.name)
+				continue
+			}
+		}
+		window.warned = true
+		changed = append(changed, window.name)
+	}
+	m.mu.Unlock()
+	if len(changed) > 0 {
+		return event.Event{Type: event.Error, Error: fmt.Errorf(
+			"%s: file changed since reading it (use :e! to reload)", strings.Join(changed, ", "))}
+	} else if len(reloaded) > 0 {
+		return event.Event{Type: event.Info, Error: fmt.Errorf(
+			"%s: file reloaded", strings.Join(reloaded, ", "))}
+	}
+	return event.Event{Type: event.Redraw}
+}
+
+// fileChanged reports whether the file of the window is changed since it was
+// read or written, and returns the current file info (nil if removed). The
+// file is expected to grow while following it, so only replacing is a change.
+func fileChanged(window *window) (os.FileInfo, bool) {
+	if window.fileInfo == nil {
+		return nil, false
+	}
+	info, err := os.Stat(window.filename)
+	if err != nil {
+		return nil, true
+	}
+	if window.getOption("follow") == true {
+		return info, !os.SameFile(info, window.fileInfo)
+	}
+	return info, !os.SameFile(info, window.fileInfo) ||
+		info.Size() != window.fileInfo.Size() ||
+		!info.ModTime().Equal(window.fileInfo.ModTime())
+}
+
+// reload opens the file of the window again discarding the changes, and keeps
+// the cursor position. The caller should hold the lock of the manager.
+func (m *Manager) reload(i int) error {
+	old := m.windows[i]
+	old.mu.Lock()
+	old.removeSwap() // the changes are discarded
+	cursor, offset := old.cursor, old.offset
+	old.mu.Unlock()
+	window, err := m.open(old.filename, false)
+	if err != nil {
+		return err
+	}
+	m.setOptions(window)
+	if old.getOption("follow") == true {
+		window.setOption("follow", true)
+	}
+	window.cursor = mathutil.MinInt64(cursor, mathutil.MaxInt64(window.length-1, 0))
+	window.offset = mathutil.MinInt64(offset, window.cursor)
+	go window.run()
+	m.windows[i] = window
+	old.close()
+	// the files read by the old window are replaced by the file opened above
+	files := m.files[:0]
+	for _, f := range m.files {
+		if f.window == old {
+			f.file.Close()
+		} else {
+			files = append(files, f)
+		}
+	}
+	m.files = files
+	return nil
+}
+
+// patchFile appends the bytes to the file, or writes the bytes at the offset
+// of the file without truncating it. The file is patched in a copy when it is
+// opened by a buffer, because the buffer reads the original file lazily.
+func (m *Manager) patchFile(r *event.Range, name string, appending bool, offset int64) (string, int64, error) {
+	window := m.windows[m.windowIndex]
+	if name == "" {
+		name = window.filename
+	}
+	if name == "" {
+		return name, 0, errors.New("no file name")
+	}
+	var err error
+	if name, err = homedir.Expand(name); err != nil {
+		return name, 0, err
+	}
+	target, perm := name, m.filePerm(name)
+	if info, err := os.Stat(name); err == nil {
+		if info.IsDir() {
+			return name, 0, fmt.Errorf("%s is a directory", name)
+		}
+		perm = info.Mode().Perm()
+		if m.opened(info) {
+			if target, err = copyFile(name, perm); err != nil {
+				return name, 0, err
+			}
+			defer os.Remove(target)
+		}
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE, perm)
+	if err != nil {
+		return name, 0, err
+	}
+	if appending {
+		_, err = f.Seek(0, io.SeekEnd)
+	} else {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return name, 0, err
+	}
+	n, err := window.writeTo(r, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && target != name {
+		err = os.Rename(target, name)
+	}
+	if err == nil && name == window.filename {
+		// the file is changed by the editor, not outside of the editor
+		window.fileInfo, _ = os.Stat(name)
+		window.warned = false
+	}
+	return name, n, err
+}
+
+// rebase opens the file which the buffer is written to, and makes the buffer
+// read the file instead of the replaced one, so that the swap file refers to
+// the offsets of the written file. The replaced files are kept open while the
+// history refers to them. The buffer is not rebased if the file cannot be
+// opened.
+func (m *Manager) rebase(window *window, name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return
+	}
+	if r, ok := window.reader.(*os.File); ok {
+		if fi, err := r.Stat(); err == nil && os.SameFile(fi, info) {
+			f.Close()
+			return
+		}
+	}
+	if err := window.rebase(f); err != nil {
+		f.Close()
+		return
+	}
+	m.files = append(m.files, file{name: name, file: f, perm: info.Mode().Perm(), window: window})
+	files := m.files[:0]
+	for _, f := range m.files {
+		if f.window == window && !window.uses(f.file) {
+			f.file.Close()
+			continue
+		}
+		files = append(files, f)
+	}
+	m.files = files
+}
+
+// opened reports whether the file is opened by a buffer.
+func (m *Manager) opened(info os.FileInfo) bool {
+	for _, f := range m.files {
+		if fi, err := f.file.Stat(); err == nil && os.SameFile(fi, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyFile copies the file to a temporary file next to it.
+func copyFile(name string, perm os.FileMode) (string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(
+		name+"-"+strconv.FormatUint(rand.Uint64(), 16),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL, perm,
+	)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(dst, src); err == nil {
+		err = dst.Close()
+	} else {
+		dst.Close()
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
 }
 
 func (m *Manager) filePerm(name string) os.FileMode {
@@ -471,10 +1383,12 @@ func (m *Manager) filePerm(name string) os.FileMode {
 
 // Close the Manager.
 func (m *Manager) Close() {
+	close(m.done)
 	for _, f := range m.files {
 		f.file.Close()
 	}
 	for _, w := range m.windows {
 		w.close()
 	}
+	m.scanWg.Wait()
 }
@@ -7,10 +7,12 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/layout"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/state"
 )
 
 func TestManagerOpenEmpty(t *testing.T) {
@@ -204,3 +206,1022 @@ func TestManagerWincmd(t *testing.T) {
 
 	wm.Close()
 }
+
+func TestManagerFindAll(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-find-all")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("Hello, world! Hello, world!\xca\xfe Hello"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.CountMatches, Arg: "/Hello/"}, "3 (0x3) matches"},
+		{event.Event{Type: event.CountMatches, Arg: "0xcafe"}, "1 (0x1) matches"},
+		{event.Event{Type: event.CountMatches, Arg: "world", Range: &event.Range{
+			From: event.Absolute{Offset: 8}, To: event.End{}}}, "1 (0x1) matches"},
+		{event.Event{Type: event.CountMatches, Arg: "0xcaf"}, "invalid hex pattern: 0xcaf"},
+		{event.Event{Type: event.CountMatches, Arg: "//"}, "empty pattern"},
+		{event.Event{Type: event.NextMatch}, "no matches"},
+		{event.Event{Type: event.FindAll, Arg: "Hello"}, "3 (0x3) matches found"},
+		{event.Event{Type: event.PreviousMatch}, "no previous matches"},
+		{event.Event{Type: event.NextMatch}, "(1 of 3)"},
+		{event.Event{Type: event.NextMatch, Count: 5}, "(3 of 3)"},
+		{event.Event{Type: event.NextMatch}, "no more matches"},
+		{event.Event{Type: event.PreviousMatch}, "(2 of 3)"},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+
+	windowStates, _, _, _ := wm.State()
+	if windowStates[0].Cursor != 14 {
+		t.Errorf("cursor should be %d but got %d", 14, windowStates[0].Cursor)
+	}
+
+	wm.windows[0].replaceBytes(0, 0, []byte("xx"))
+	go wm.Emit(event.Event{Type: event.PreviousMatch})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "no matches" {
+		t.Errorf("matches should be cleared after the changes but got: %v", e.Error)
+	}
+
+	_, _, _, stop, err := wm.windows[0].startFind(nil)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.FindAll, Arg: "Hello"})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "3 (0x3) matches found" {
+		t.Errorf("message should be %q but got: %v", "3 (0x3) matches found", e.Error)
+	}
+	if err := wm.windows[0].setMatches(nil, stop); err != errFindStopped {
+		t.Errorf("previous scan should be stopped but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.NextMatch})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "(1 of 3)" {
+		t.Errorf("message should be %q but got: %v", "(1 of 3)", e.Error)
+	}
+
+	_, _, _, stop, _ = wm.windows[0].startFind(nil)
+	wm.windows[0].replaceBytes(0, 2, nil)
+	if err := wm.windows[0].findInterrupted(stop); err != errFindChanged {
+		t.Errorf("scan should be interrupted by the changes but got: %v", err)
+	}
+
+	defer func(max int) { maxMatches = max }(maxMatches)
+	maxMatches = 1
+	go wm.Emit(event.Event{Type: event.FindAll, Arg: "Hello"})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "1 (0x1) matches found (the rest are not stored)" {
+		t.Errorf("message should be %q but got: %v", "1 (0x1) matches found (the rest are not stored)", e.Error)
+	}
+
+	// the result of the scan is not sent after closing
+	wm.Emit(event.Event{Type: event.CountMatches, Arg: "Hello"})
+	wm.Close()
+}
+
+func TestManagerTemplate(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-template")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("xxBED\x00\x00\x02\x00\x00\x00\x10\x00\x00\x00\x20"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	g, err := ioutil.TempFile("", "bed-test-manager-template")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(g.Name())
+	if _, err := g.WriteString("endian big\nchar[4] magic\nu16 count\nu32[count] values\n"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.ApplyTemplate, CmdName: "template"}, "no template applied"},
+		{event.Event{Type: event.GotoField, CmdName: "field"}, "an argument is required for field"},
+		{event.Event{Type: event.ApplyTemplate, Arg: g.Name() + " $"}, "magic: unexpected end of data"},
+		{event.Event{Type: event.ApplyTemplate, Arg: g.Name() + " 2"}, ""},
+		{event.Event{Type: event.GotoField, Arg: "value"}, "field not found: value"},
+		{event.Event{Type: event.GotoField, Arg: "values[1]"}, ""},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if testCase.expected == "" {
+			if e.Type != event.Redraw {
+				t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+			}
+		} else if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+
+	windowStates, _, _, _ := wm.State()
+	s := windowStates[0]
+	if s.Cursor != 12 {
+		t.Errorf("cursor should be %d but got %d", 12, s.Cursor)
+	}
+	if s.Pane == nil {
+		t.Fatalf("s.Pane should not be nil")
+	}
+	expected := []string{
+		"00000002 magic: char[4] = \"BED\\x00\"",
+		"00000006 count: u16be = 2 (0x2)",
+		"00000008 values: u32be[count]",
+		"00000008   [0]: u32be = 16 (0x10)",
+		"0000000c   [1]: u32be = 32 (0x20)",
+	}
+	if !reflect.DeepEqual(s.Pane.Lines, expected) {
+		t.Errorf("s.Pane.Lines should be %q but got %q", expected, s.Pane.Lines)
+	}
+	if s.Pane.Index != 4 {
+		t.Errorf("s.Pane.Index should be %d but got %d", 4, s.Pane.Index)
+	}
+	if len(s.Highlights) != 4 {
+		t.Errorf("len(s.Highlights) should be %d but got %d", 4, len(s.Highlights))
+	}
+
+	go wm.Emit(event.Event{Type: event.ApplyTemplate})
+	<-eventCh
+	windowStates, _, _, _ = wm.State()
+	if windowStates[0].Pane != nil || windowStates[0].Highlights != nil {
+		t.Errorf("the template pane should be closed")
+	}
+	wm.Close()
+}
+
+func TestManagerFormat(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	f, err := ioutil.TempFile("", "bed-test-manager-format")
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("\x89PNG\r\n\x1a\n" +
+		"\x00\x00\x00\x01IHDR\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x02IDATab\x00\x00\x00\x00" +
+		"\x00\x00\x00\x02IDATcd\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00IEND\x00\x00\x00\x00"); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if err := wm.Open(f.Name()); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	windowStates, _, _, _ := wm.State()
+	if windowStates[0].Format != "PNG" {
+		t.Errorf("format should be %q but got %q", "PNG", windowStates[0].Format)
+	}
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.GotoEntry, CmdName: "goto", Arg: "chunk"}, "goto requires section, chunk or entry and its name"},
+		{event.Event{Type: event.GotoEntry, Arg: "section .text"}, "PNG has no sections"},
+		{event.Event{Type: event.GotoEntry, Arg: "chunk tEXt"}, "chunk not found: tEXt"},
+		{event.Event{Type: event.GotoEntry, Arg: "chunk IDAT"}, ""},
+		{event.Event{Type: event.GotoEntry, Arg: "chunk IDAT"}, ""},
+		{event.Event{Type: event.ToggleFormat}, ""},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if testCase.expected == "" {
+			if e.Type != event.Redraw {
+				t.Errorf("event type should be %d but got: %d", event.Redraw, e.Type)
+			}
+		} else if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+
+	windowStates, _, _, _ = wm.State()
+	s := windowStates[0]
+	if s.Cursor != 35 {
+		t.Errorf("cursor should be %d but got %d", 35, s.Cursor)
+	}
+	if s.Pane == nil {
+		t.Fatalf("s.Pane should not be nil")
+	}
+	if expected := "PNG chunks               offset     size"; s.Pane.Title != expected {
+		t.Errorf("s.Pane.Title should be %q but got %q", expected, s.Pane.Title)
+	}
+	expected := []string{
+		"IHDR                     00000008   d",
+		"IDAT                     00000015   e",
+		"IDAT                     00000023   e",
+		"IEND                     00000031   c",
+	}
+	if !reflect.DeepEqual(s.Pane.Lines, expected) {
+		t.Errorf("s.Pane.Lines should be %q but got %q", expected, s.Pane.Lines)
+	}
+	if s.Pane.Index != 2 {
+		t.Errorf("s.Pane.Index should be %d but got %d", 2, s.Pane.Index)
+	}
+
+	wm.windows[0].replaceBytes(0, 1, []byte("x"))
+	go wm.Emit(event.Event{Type: event.Write})
+	if e := <-eventCh; e.Type != event.Info {
+		t.Errorf("write should succeed but got: %v", e.Error)
+	}
+	windowStates, _, _, _ = wm.State()
+	if s := windowStates[0]; s.Format != "" || s.Pane != nil {
+		t.Errorf("format should be recognized again after writing but got %q", s.Format)
+	}
+	wm.Close()
+}
+
+func TestManagerAnnotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-manager-annotation")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.bin")
+	if err := ioutil.WriteFile(name, []byte("0123456789abcdef0123456789abcdef"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	newManager := func() (*Manager, chan event.Event) {
+		wm := NewManager()
+		eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+		wm.Init(eventCh, redrawCh)
+		go func() {
+			for {
+				<-redrawCh
+			}
+		}()
+		wm.SetSize(110, 20)
+		if err := wm.Open(name); err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		_, _, _, _ = wm.State()
+		return wm, eventCh
+	}
+	wm, eventCh := newManager()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.NextAnnotation}, "no annotations"},
+		{event.Event{Type: event.Annotate, CmdName: "annotate"}, "an argument is required for annotate"},
+		{event.Event{Type: event.Annotate, Arg: `"broken`}, `invalid note: "broken`},
+		{event.Event{Type: event.Annotate, Arg: `red "magic number"`, Range: &event.Range{
+			From: event.Absolute{Offset: 2}, To: event.Absolute{Offset: 5}}}, ""},
+		{event.Event{Type: event.Annotate, Arg: "length", Range: &event.Range{
+			From: event.Absolute{Offset: 8}}}, ""},
+		{event.Event{Type: event.ToggleAnnotations}, ""},
+		{event.Event{Type: event.NextAnnotation}, "magic number"},
+		{event.Event{Type: event.NextAnnotation}, "length"},
+		{event.Event{Type: event.NextAnnotation}, "no more annotations"},
+		{event.Event{Type: event.PreviousAnnotation, Count: 3}, "magic number"},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if testCase.expected == "" {
+			if e.Type != event.Redraw {
+				t.Errorf("event type should be %d but got: %d (%v)", event.Redraw, e.Type, e.Error)
+			}
+		} else if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+
+	windowStates, _, _, _ := wm.State()
+	s := windowStates[0]
+	if s.Pane == nil {
+		t.Fatalf("s.Pane should not be nil")
+	}
+	expected := []string{
+		"00000002-00000005 red    magic number",
+		"00000008-00000008 green  length",
+	}
+	if !reflect.DeepEqual(s.Pane.Lines, expected) {
+		t.Errorf("s.Pane.Lines should be %q but got %q", expected, s.Pane.Lines)
+	}
+	if s.Pane.Index != 0 {
+		t.Errorf("s.Pane.Index should be %d but got %d", 0, s.Pane.Index)
+	}
+	highlights := []state.Highlight{{From: 2, To: 6, Color: 2}, {From: 8, To: 9, Color: 1}}
+	if !reflect.DeepEqual(s.Highlights, highlights) {
+		t.Errorf("s.Highlights should be %+v but got %+v", highlights, s.Highlights)
+	}
+	wm.Close()
+
+	wm, eventCh = newManager()
+	windowStates, _, _, _ = wm.State()
+	if !reflect.DeepEqual(windowStates[0].Highlights, highlights) {
+		t.Errorf("annotations should be reloaded but got %+v", windowStates[0].Highlights)
+	}
+	go wm.Emit(event.Event{Type: event.Unannotate, Range: &event.Range{
+		From: event.Absolute{Offset: 0}, To: event.End{}}})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "2 annotations removed" {
+		t.Errorf("message should be %q but got: %v", "2 annotations removed", e.Error)
+	}
+	if _, err := os.Stat(annotationPath(name)); !os.IsNotExist(err) {
+		t.Errorf("the annotation file should be removed but got: %v", err)
+	}
+	wm.Close()
+}
+
+func TestManagerFilter(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	window := wm.windows[0]
+	window.replaceBytes(0, 0, []byte("Hello, world!"))
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+		bytes    string
+	}{
+		{event.Event{Type: event.Filter, CmdName: "!"}, "an argument is required for !", "Hello, world!"},
+		{event.Event{Type: event.Filter, Arg: "echo Hi"}, "Hi", "Hello, world!"},
+		{event.Event{Type: event.Filter, Arg: "tr a-z A-Z", Range: &event.Range{
+			From: event.Absolute{Offset: 7}, To: event.Absolute{Offset: 11}}},
+			"5 (0x5) bytes filtered to 5 (0x5) bytes", "Hello, WORLD!"},
+		{event.Event{Type: event.Filter, Arg: "echo error >&2; exit 1", Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.End{}}},
+			"echo error >&2; exit 1: error", "Hello, WORLD!"},
+		{event.Event{Type: event.Filter, Arg: "head -c 2", Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 4}}},
+			"5 (0x5) bytes filtered to 2 (0x2) bytes", "He, WORLD!"},
+		{event.Event{Type: event.Read, Arg: "!printf 'abc'"}, "3 (0x3) bytes read", "abcHe, WORLD!"},
+		{event.Event{Type: event.Read, Arg: "!false"}, "false: exit status 1", "abcHe, WORLD!"},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+		windowStates, _, _, _ := wm.State()
+		s := windowStates[0]
+		if got := string(s.Bytes[:s.Length]); got != testCase.bytes {
+			t.Errorf("bytes should be %q but got %q", testCase.bytes, got)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-manager-read")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.bin")
+	if err := ioutil.WriteFile(name, []byte("xyz"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+		bytes    string
+		cursor   int64
+	}{
+		{event.Event{Type: event.Read, CmdName: "r[ead]"}, "an argument is required for r[ead]", "", 0},
+		{event.Event{Type: event.Read, CmdName: "r[ead]", Arg: "++replace"}, "an argument is required for r[ead]", "", 0},
+		{event.Event{Type: event.Read, Arg: dir}, dir + " is a directory", "", 0},
+		{event.Event{Type: event.Read, Arg: name}, name + ": 3 (0x3) bytes read", "xyz", 0},
+		{event.Event{Type: event.Read, Arg: "!printf 0123456789"}, "10 (0xa) bytes read", "0123456789xyz", 0},
+		{event.Event{Type: event.Read, Arg: name, Range: &event.Range{From: event.Absolute{Offset: 4}}},
+			name + ": 3 (0x3) bytes read", "0123xyz456789xyz", 4},
+		{event.Event{Type: event.Read, Arg: name, Range: &event.Range{From: event.End{}}},
+			name + ": 3 (0x3) bytes read", "0123xyz456789xyzxyz", 16},
+		{event.Event{Type: event.Read, Arg: "++replace " + name, Range: &event.Range{From: event.Absolute{Offset: 0}}},
+			name + ": 3 (0x3) bytes replaced", "xyz3xyz456789xyzxyz", 0},
+		{event.Event{Type: event.Read, Arg: "++replace !printf ABCDEF", Range: &event.Range{From: event.End{Offset: -2}}},
+			"3 (0x3) bytes replaced", "xyz3xyz456789xyzABC", 16},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+		windowStates, _, _, _ := wm.State()
+		s := windowStates[0]
+		if got := string(s.Bytes[:s.Length]); got != testCase.bytes {
+			t.Errorf("bytes should be %q but got %q", testCase.bytes, got)
+		}
+		if s.Cursor != testCase.cursor {
+			t.Errorf("s.Cursor should be %d but got %d", testCase.cursor, s.Cursor)
+		}
+	}
+	wm.Close()
+}
+
+func TestManagerWritePatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-manager-write-patch")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name, other := filepath.Join(dir, "test.bin"), filepath.Join(dir, "other.bin")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+		filename string
+		contents string
+	}{
+		{event.Event{Type: event.Write, Arg: ">> " + other, Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 2}}},
+			other + ": 3 (0x3) bytes appended", other, "012"},
+		{event.Event{Type: event.Write, Arg: ">>" + other},
+			other + ": 10 (0xa) bytes appended", other, "0120123456789"},
+		{event.Event{Type: event.Write, Arg: "++offset=2 " + other, Range: &event.Range{
+			From: event.Absolute{Offset: 5}, To: event.Absolute{Offset: 6}}},
+			other + ": 2 (0x2) bytes written at 0x2", other, "0156123456789"},
+		{event.Event{Type: event.Write, Arg: "++offset=0x4", Range: &event.Range{
+			From: event.Absolute{Offset: 0}, To: event.Absolute{Offset: 1}}},
+			name + ": 2 (0x2) bytes written at 0x4", name, "0123016789"},
+		{event.Event{Type: event.Write, Arg: "++offset=x " + other}, "invalid offset: x", other, "0156123456789"},
+		{event.Event{Type: event.Write, CmdName: "w[rite]", Range: &event.Range{From: event.Absolute{Offset: 0}}},
+			"cannot overwrite partially with w[rite]", name, "0123016789"},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+		bs, err := ioutil.ReadFile(testCase.filename)
+		if err != nil {
+			t.Errorf("err should be nil but got: %v", err)
+		}
+		if string(bs) != testCase.contents {
+			t.Errorf("file contents should be %q but got %q", testCase.contents, string(bs))
+		}
+	}
+
+	windowStates, _, _, _ := wm.State()
+	s := windowStates[0]
+	if got := string(s.Bytes[:s.Length]); got != "0123456789" {
+		t.Errorf("bytes should be %q but got %q", "0123456789", got)
+	}
+	wm.Close()
+}
+
+func TestManagerSaveAsUpdateWriteAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-manager-write-all")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name, other := filepath.Join(dir, "test.bin"), filepath.Join(dir, "other.bin")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	wm.Emit(event.Event{Type: event.SwitchFocus})
+	go wm.Emit(event.Event{Type: event.Update})
+	if e := <-eventCh; e.Type != event.Redraw {
+		t.Errorf("update should not write unmodified buffer but got: %v", e.Error)
+	}
+
+	wm.windows[0].replaceBytes(0, 2, []byte("ab"))
+	windowStates, _, _, _ := wm.State()
+	if !windowStates[0].Modified {
+		t.Errorf("window should be modified")
+	}
+	go wm.Emit(event.Event{Type: event.Update})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": 10 (0xa) bytes written" {
+		t.Errorf("update should write modified buffer but got: %v", e.Error)
+	}
+	windowStates, _, _, _ = wm.State()
+	if windowStates[0].Modified {
+		t.Errorf("window should not be modified after writing")
+	}
+
+	wm.windows[0].mu.Lock()
+	wm.windows[0].undo(1)
+	wm.windows[0].mu.Unlock()
+	go wm.Emit(event.Event{Type: event.Update})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": 10 (0xa) bytes written" {
+		t.Errorf("update should write the undone buffer but got: %v", e.Error)
+	}
+	wm.windows[0].mu.Lock()
+	wm.windows[0].undo(1)
+	wm.windows[0].mu.Unlock()
+	windowStates, _, _, _ = wm.State()
+	if windowStates[0].Modified {
+		t.Errorf("window should not be modified by undo at the oldest change")
+	}
+	wm.windows[0].mu.Lock()
+	wm.windows[0].redo(1)
+	wm.windows[0].mu.Unlock()
+	go wm.Emit(event.Event{Type: event.Update})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": 10 (0xa) bytes written" {
+		t.Errorf("update should write the redone buffer but got: %v", e.Error)
+	}
+
+	go wm.Emit(event.Event{Type: event.SaveAs, CmdName: "sav[eas]"})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "an argument is required for sav[eas]" {
+		t.Errorf("saveas should require an argument but got: %v", e.Error)
+	}
+	wm.windows[0].replaceBytes(9, 10, []byte("z"))
+	wm.windows[0].annotations = []annotation{{From: 0, To: 1, Color: "blue"}}
+	if err := saveAnnotations(name, wm.windows[0].annotations); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := ioutil.WriteFile(other, nil, 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.SaveAs, Arg: other})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != other+": file exists (add ! to override)" {
+		t.Errorf("saveas should not overwrite the existing file but got: %v", e.Error)
+	}
+	go wm.Emit(event.Event{Type: event.SaveAs, Arg: other, Bang: true})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != other+": 10 (0xa) bytes written" {
+		t.Errorf("saveas should write the buffer but got: %v", e.Error)
+	}
+	windowStates, _, _, _ = wm.State()
+	if windowStates[0].Name != "other.bin" || windowStates[0].Modified {
+		t.Errorf("window should be renamed and not modified but got %q", windowStates[0].Name)
+	}
+	if _, err := os.Stat(annotationPath(name)); !os.IsNotExist(err) {
+		t.Errorf("annotations should be moved from the old file but got: %v", err)
+	}
+	if annotations, err := loadAnnotations(other); err != nil || len(annotations) != 1 {
+		t.Errorf("annotations should be moved to the new file but got %v (err: %v)", annotations, err)
+	}
+	if wm.windows[0].swapName != swapPath(other) {
+		t.Errorf("swap file should be %q but got %q", swapPath(other), wm.windows[0].swapName)
+	}
+	for file, contents := range map[string]string{name: "ab23456789", other: "ab2345678z"} {
+		if bs, err := ioutil.ReadFile(file); err != nil || string(bs) != contents {
+			t.Errorf("file contents should be %q but got %q (err: %v)", contents, string(bs), err)
+		}
+	}
+
+	if err := wm.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	wm.windows[0].replaceBytes(0, 1, []byte("x"))
+	wm.windows[1].replaceBytes(0, 1, []byte("y"))
+	go wm.Emit(event.Event{Type: event.WriteAll})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "2 buffers written" {
+		t.Errorf("wall should write all the buffers but got: %v", e.Error)
+	}
+	for file, contents := range map[string]string{name: "yb23456789", other: "xb2345678z"} {
+		if bs, err := ioutil.ReadFile(file); err != nil || string(bs) != contents {
+			t.Errorf("file contents should be %q but got %q (err: %v)", contents, string(bs), err)
+		}
+	}
+
+	wm.windows[1].replaceBytes(1, 2, []byte("w"))
+	go wm.Emit(event.Event{Type: event.WriteAllQuit})
+	if e := <-eventCh; e.Type != event.QuitAll {
+		t.Errorf("xall should emit QuitAll but got: %v", e)
+	}
+	if bs, err := ioutil.ReadFile(name); err != nil || string(bs) != "yw23456789" {
+		t.Errorf("file contents should be %q but got %q (err: %v)", "yw23456789", string(bs), err)
+	}
+	wm.Close()
+}
+
+func TestManagerCheckTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bed-test-manager-check-time")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.bin")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	go wm.Emit(event.Event{Type: event.CheckTime, CmdName: "checkt[ime]"})
+	if e := <-eventCh; e.Type != event.Redraw {
+		t.Errorf("checktime should not warn unchanged files but got: %v", e.Error)
+	}
+
+	if err := ioutil.WriteFile(name, []byte("abcdefghijklmnop"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.CheckTime}, "test.bin: file changed since reading it (use :e! to reload)"},
+		{event.Event{Type: event.CheckTime}, "-"},
+		{event.Event{Type: event.CheckTime, CmdName: "checkt[ime]"},
+			"test.bin: file changed since reading it (use :e! to reload)"},
+		{event.Event{Type: event.Write}, name + ": file changed since reading it (add ! to override)"},
+		{event.Event{Type: event.Edit}, ""},
+		{event.Event{Type: event.CheckTime, CmdName: "checkt[ime]"}, ""},
+	} {
+		go wm.Emit(testCase.e)
+		if testCase.expected == "-" {
+			select {
+			case e := <-eventCh:
+				t.Errorf("the periodic check should not send an event but got: %v", e)
+			case <-time.After(50 * time.Millisecond):
+			}
+			continue
+		}
+		e := <-eventCh
+		if testCase.expected == "" {
+			if e.Type != event.Redraw {
+				t.Errorf("event type should be Redraw but got: %v", e.Error)
+			}
+		} else if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+	windowStates, _, windowIndex, _ := wm.State()
+	s := windowStates[windowIndex]
+	if got := string(s.Bytes[:s.Length]); got != "abcdefghijklmnop" {
+		t.Errorf("bytes should be %q but got %q", "abcdefghijklmnop", got)
+	}
+
+	wm.windows[wm.windowIndex].replaceBytes(0, 1, []byte("x"))
+	go wm.Emit(event.Event{Type: event.Edit})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "no write since last change (add ! to override)" {
+		t.Errorf("edit should not discard changes but got: %v", e.Error)
+	}
+	// the buffer reads the original file, which should be replaced by renaming
+	if err := ioutil.WriteFile(name+"~", []byte("0123"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if err := os.Rename(name+"~", name); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.Write})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": file changed since reading it (add ! to override)" {
+		t.Errorf("write should fail on changed file but got: %v", e.Error)
+	}
+	go wm.Emit(event.Event{Type: event.Write, Bang: true})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": 16 (0x10) bytes written" {
+		t.Errorf("forced write should write the file but got: %v", e.Error)
+	}
+
+	go wm.Emit(event.Event{Type: event.Set, Arg: "autoread"})
+	<-eventCh
+	if err := ioutil.WriteFile(name, []byte("0123"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	go wm.Emit(event.Event{Type: event.CheckTime})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != "test.bin: file reloaded" {
+		t.Errorf("checktime should reload the file but got: %v", e.Error)
+	}
+	windowStates, _, windowIndex, _ = wm.State()
+	s = windowStates[windowIndex]
+	if got := string(s.Bytes[:s.Length]); got != "0123" {
+		t.Errorf("bytes should be %q but got %q", "0123", got)
+	}
+
+	wm.windows[wm.windowIndex].replaceBytes(0, 1, []byte("x"))
+	go wm.Emit(event.Event{Type: event.Edit, Bang: true})
+	<-eventCh
+	windowStates, _, windowIndex, _ = wm.State()
+	s = windowStates[windowIndex]
+	if got := string(s.Bytes[:s.Length]); got != "0123" || s.Modified {
+		t.Errorf("bytes should be %q but got %q", "0123", got)
+	}
+	if len(wm.files) != len(wm.windows) {
+		t.Errorf("files should be replaced on reloading but got %d files for %d windows",
+			len(wm.files), len(wm.windows))
+	}
+
+	go wm.Emit(event.Event{Type: event.Write, Arg: ">>"})
+	if e := <-eventCh; e.Error == nil || e.Error.Error() != name+": 4 (0x4) bytes appended" {
+		t.Errorf("write should append to the file but got: %v", e.Error)
+	}
+	go wm.Emit(event.Event{Type: event.CheckTime, CmdName: "checkt[ime]"})
+	if e := <-eventCh; e.Type != event.Redraw {
+		t.Errorf("checktime should not warn the file patched by the editor but got: %v", e.Error)
+	}
+	wm.Close()
+}
+
+func TestManagerSwapRecover(t *testing.T) {
+	defer func(interval time.Duration) { swapInterval = interval }(swapInterval)
+	swapInterval = 10 * time.Millisecond
+	dir, err := ioutil.TempDir("", "bed-test-manager-swap")
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "test.bin")
+	if err := ioutil.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	newManager := func() (*Manager, chan event.Event) {
+		wm := NewManager()
+		eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+		wm.Init(eventCh, redrawCh)
+		go func() {
+			for {
+				<-redrawCh
+			}
+		}()
+		wm.SetSize(110, 20)
+		return wm, eventCh
+	}
+
+	wm, _ := newManager()
+	if err := wm.Recover(name); err == nil || err.Error() != "no swap file found for "+name {
+		t.Errorf("recover should fail without swap file but got: %v", err)
+	}
+	if err := wm.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+	wm.windows[0].replaceBytes(2, 4, []byte("abcde"))
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(swapPath(name)); err != nil {
+		t.Fatalf("swap file should be written but got: %v", err)
+	}
+
+	// another manager warns the existing swap file, and keeps it
+	wm2, eventCh2 := newManager()
+	if err := wm2.Open(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if e := <-eventCh2; e.Error == nil || e.Error.Error() != ".test.bin.bed.swp: swap file exists (recover with bed -r, or remove it)" {
+		t.Errorf("open should warn the swap file but got: %v", e.Error)
+	}
+	wm2.Close()
+	if _, err := os.Stat(swapPath(name)); err != nil {
+		t.Fatalf("swap file should not be removed but got: %v", err)
+	}
+
+	wm3, eventCh3 := newManager()
+	if err := wm3.Recover(name); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if e := <-eventCh3; e.Error == nil || e.Error.Error() != "test.bin: recovered from the swap file" {
+		t.Errorf("recover should report but got: %v", e.Error)
+	}
+	windowStates, _, _, _ := wm3.State()
+	s := windowStates[0]
+	if got := string(s.Bytes[:s.Length]); got != "01abcde456789" || !s.Modified {
+		t.Errorf("bytes should be %q and modified but got %q", "01abcde456789", got)
+	}
+	go wm3.Emit(event.Event{Type: event.Write})
+	if e := <-eventCh3; e.Error == nil || e.Error.Error() != name+": 13 (0xd) bytes written" {
+		t.Errorf("write should write the recovered buffer but got: %v", e.Error)
+	}
+	if _, err := os.Stat(swapPath(name)); !os.IsNotExist(err) {
+		t.Errorf("swap file should be removed after writing but got: %v", err)
+	}
+
+	// the swap file after writing refers to the written file
+	wm3.windows[0].replaceBytes(0, 1, []byte("x"))
+	time.Sleep(100 * time.Millisecond)
+	wm4, eventCh4 := newManager()
+	if err := wm4.Recover(name); err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if e := <-eventCh4; e.Error == nil || e.Error.Error() != "test.bin: recovered from the swap file" {
+		t.Errorf("recover should report but got: %v", e.Error)
+	}
+	windowStates, _, _, _ = wm4.State()
+	s = windowStates[0]
+	if got := string(s.Bytes[:s.Length]); got != "x1abcde456789" || !s.Modified {
+		t.Errorf("bytes should be %q and modified but got %q", "x1abcde456789", got)
+	}
+	wm4.Close()
+
+	// the written files are closed unless the history refers to them
+	for i := 0; i < 3; i++ {
+		go wm3.Emit(event.Event{Type: event.Write})
+		if e := <-eventCh3; e.Error == nil || e.Error.Error() != name+": 13 (0xd) bytes written" {
+			t.Errorf("write should write the buffer but got: %v", e.Error)
+		}
+		if i == 0 {
+			continue
+		}
+		if len(wm3.files) != 3 {
+			t.Errorf("files should not be kept open but got %d files", len(wm3.files))
+		}
+	}
+
+	// the error of writing the swap file is reported once
+	wm3.windows[0].mu.Lock()
+	wm3.windows[0].swapName = filepath.Join(dir, "none", swapPath(name))
+	wm3.windows[0].mu.Unlock()
+	wm3.windows[0].replaceBytes(0, 1, []byte("y"))
+	if e := <-eventCh3; e.Error == nil || !strings.HasPrefix(e.Error.Error(),
+		".test.bin.bed.swp: failed to write the swap file: ") {
+		t.Errorf("writing the swap file should report the error but got: %v", e.Error)
+	}
+	wm3.windows[0].replaceBytes(0, 1, []byte("z"))
+	select {
+	case e := <-eventCh3:
+		t.Errorf("the error should be reported once but got: %v", e.Error)
+	case <-time.After(100 * time.Millisecond):
+	}
+	wm3.Close()
+	wm.Close()
+}
+
+func TestManagerSet(t *testing.T) {
+	wm := NewManager()
+	eventCh, redrawCh := make(chan event.Event), make(chan struct{})
+	wm.Init(eventCh, redrawCh)
+	go func() {
+		for {
+			<-redrawCh
+		}
+	}()
+	wm.SetSize(110, 20)
+	if err := wm.Open(""); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	_, _, _, _ = wm.State()
+
+	for _, testCase := range []struct {
+		e        event.Event
+		expected string
+	}{
+		{event.Event{Type: event.Set, CmdName: "set"}, "an argument is required for set"},
+		{event.Event{Type: event.Set, Arg: "columns!"}, "invalid argument: columns!"},
+		{event.Event{Type: event.Set, Arg: "nocolumns"}, "unknown option: nocolumns"},
+		{event.Event{Type: event.Set, Arg: "foo=1"}, "unknown option: foo"},
+		{event.Event{Type: event.Set, Arg: "group=3"}, "invalid value for group: 3"},
+		{event.Event{Type: event.Set, Arg: "columns=-1"}, "invalid value for columns: -1"},
+		{event.Event{Type: event.Set, Arg: "display=ascii"}, "invalid value for display: ascii"},
+		{event.Event{Type: event.Set, Arg: "encoding=utf-32"}, "invalid value for encoding: utf-32"},
+		{event.Event{Type: event.SetLocal, Arg: "encoding=Shift_JIS"}, ""},
+		{event.Event{Type: event.Set, Arg: "columns=20"}, ""},
+		{event.Event{Type: event.SetLocal, Arg: "group=4"}, ""},
+		{event.Event{Type: event.Set, Arg: "columns? group encoding?"}, "columns=20  group=4  encoding=shift_jis"},
+		{event.Event{Type: event.Vnew}, ""},
+		{event.Event{Type: event.SetLocal, Arg: "columns=0"}, ""},
+		{event.Event{Type: event.Set, Arg: "group=2"}, ""},
+		{event.Event{Type: event.Set, Arg: "columns? group? encoding?"}, "columns=0  group=2  encoding=ascii"},
+	} {
+		go wm.Emit(testCase.e)
+		e := <-eventCh
+		if testCase.expected == "" {
+			if e.Type != event.Redraw {
+				t.Errorf("event type should be %d but got: %d (%v)", event.Redraw, e.Type, e.Error)
+			}
+		} else if e.Error == nil || e.Error.Error() != testCase.expected {
+			t.Errorf("message should be %q but got: %v", testCase.expected, e.Error)
+		}
+	}
+
+	windowStates, _, _, _ := wm.State()
+	if windowStates[0].Width != 20 || windowStates[0].Group != 4 {
+		t.Errorf("width and group should be 20 and 4 but got %d and %d",
+			windowStates[0].Width, windowStates[0].Group)
+	}
+	if windowStates[0].Encoding != "shift_jis" {
+		t.Errorf("encoding should be %q but got %q", "shift_jis", windowStates[0].Encoding)
+	}
+	if windowStates[1].Width != 8 || windowStates[1].Group != 2 {
+		t.Errorf("width and group should be 8 and 2 but got %d and %d",
+			windowStates[1].Width, windowStates[1].Group)
+	}
+
+	for _, testCase := range []struct {
+		arg   string
+		width int
+	}{
+		{"columns=0x30", 48},
+		{"columns=0", 8},
+		{"display=binary", 4},
+	} {
+		go wm.Emit(event.Event{Type: event.Set, Arg: testCase.arg})
+		<-eventCh
+		windowStates, _, _, _ = wm.State()
+		if windowStates[1].Width != testCase.width {
+			t.Errorf("width should be %d but got %d", testCase.width, windowStates[1].Width)
+		}
+	}
+
+	go wm.Emit(event.Event{Type: event.New})
+	<-eventCh
+	windowStates, _, _, _ = wm.State()
+	if windowStates[2].Width != 4 || windowStates[2].Group != 2 || windowStates[2].Display != "binary" {
+		t.Errorf("width, group and display should be 4, 2 and binary but got %d, %d and %s",
+			windowStates[2].Width, windowStates[2].Group, windowStates[2].Display)
+	}
+	wm.Close()
+}
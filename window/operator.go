@@ -0,0 +1,321 @@
+package window
+
+import (
+	"strconv"
+
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/mode"
+)
+
+// operate applies the pending operator to the range of the motion, and
+// returns the mode to record the change in the history. The operator applies
+// to the rows of the cursor when the same operator is given as the motion.
+func (w *window) operate(e event.Event) mode.Mode {
+	operator, count, register := w.operator, w.opCount, w.opRegister
+	w.operator, w.opCount, w.opRegister = event.Nop, 0, 0
+	if operator == event.Nop || e.Type == event.CancelOperator {
+		return mode.Normal
+	}
+	if count > 0 {
+		e.Count = count * mathutil.MaxInt64(e.Count, 1)
+	}
+	offset, cursor := w.offset, w.cursor
+	from, to := cursor, cursor-1
+	if e.Type == operator {
+		from = cursor - cursor%w.width
+		to = mathutil.MinInt64(
+			(cursor/w.width+mathutil.MaxInt64(e.Count, 1))*w.width, w.length) - 1
+	} else if e.Mode = mode.Normal; w.motion(e) {
+		switch e.Type {
+		case event.CursorUp, event.CursorDown, event.PageUp, event.PageDown,
+			event.PageUpHalf, event.PageDownHalf, event.PageTop, event.PageEnd:
+			from = mathutil.MinInt64(cursor, w.cursor)
+			from -= from % w.width
+			to = mathutil.MaxInt64(cursor, w.cursor)
+			to = mathutil.MinInt64(to-to%w.width+w.width, w.length) - 1
+		case event.CursorRight:
+			to = mathutil.MinInt64(mathutil.MinInt64(
+				cursor+mathutil.MaxInt64(e.Count, 1), cursor-cursor%w.width+w.width), w.length) - 1
+		case event.CursorNext:
+			to = mathutil.MinInt64(cursor+mathutil.MaxInt64(e.Count, 1), w.length) - 1
+		case event.CursorEnd:
+			to = w.cursor
+		case event.FindByte:
+			if w.cursor > cursor {
+				to = w.cursor
+			}
+		default:
+			if w.cursor < cursor {
+				from, to = w.cursor, cursor-1
+			} else {
+				to = w.cursor - 1
+			}
+		}
+	}
+	if w.length == 0 && operator == event.OperatorChange {
+		w.startInsert()
+		return mode.Insert
+	}
+	if from > to || w.length == 0 {
+		// the operator is not applied when the motion fails
+		w.offset, w.cursor = offset, cursor
+		return mode.Normal
+	}
+	w.applyOperator(operator, register, from, to)
+	if operator == event.OperatorChange {
+		return mode.Insert
+	}
+	return mode.Normal
+}
+
+// maxRegisterSize is the maximum size of the bytes which the operators store
+// to the registers, so that deleting a large range does not read the bytes.
+const maxRegisterSize = 16 * 1024 * 1024
+
+// operatorChunk is the size of the chunk to replace the bytes in a range, so
+// that a large range is not read at once.
+const operatorChunk = 64 * 1024
+
+// applyOperator applies the operator to the bytes from the offset to the end
+// offset (inclusive), and moves the cursor to the head of the range. The
+// yanked or deleted bytes are stored to the register if it is specified.
+func (w *window) applyOperator(operator event.Type, register rune, from, to int64) {
+	var bytes []byte
+	if w.registers != nil && to-from+1 <= maxRegisterSize &&
+		operator != event.OperatorSwitchCase {
+		var err error
+		if _, bytes, err = w.readBytes(from, int(to-from+1)); err != nil {
+			return
+		}
+	}
+	switch operator {
+	case event.OperatorYank:
+		if bytes != nil {
+			w.setRegisters(bytes, register, '0')
+		}
+	case event.OperatorDelete, event.OperatorChange:
+		if bytes != nil {
+			w.setRegisters(bytes, register, '1')
+		}
+		w.buffer.DeleteRange(from, to+1)
+		w.length -= to - from + 1
+		w.changedTick++
+	case event.OperatorSwitchCase:
+		w.mapBytes(from, to, func(b byte) byte {
+			if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' {
+				return b ^ 0x20
+			}
+			return b
+		})
+	}
+	w.cursorGotoOffset(from)
+	if operator == event.OperatorChange {
+		w.cursor = from
+		w.startInsert()
+	}
+}
+
+// setRegisters stores the bytes to the unnamed register and the register of
+// the name, or the specified register instead.
+func (w *window) setRegisters(bytes []byte, register, name rune) {
+	if w.registers == nil {
+		return
+	}
+	if register != 0 && register != '"' {
+		name = register
+	}
+	_ = w.registers.Set('"', bytes)
+	_ = w.registers.Set(name, bytes)
+}
+
+// findByte moves the cursor to the count-th occurrence of the byte in the
+// hexadecimal notation. The cursor does not move if not found.
+func (w *window) findByte(arg string, count int64, forward bool) {
+	b, err := strconv.ParseUint(arg, 16, 8)
+	if err != nil {
+		return
+	}
+	offset, cursor := w.offset, w.cursor
+	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
+		prev := w.cursor
+		w.search(string([]byte{byte(b)}), forward)
+		if w.cursor == prev {
+			w.offset, w.cursor = offset, cursor
+			return
+		}
+	}
+}
+
+// operateVisual applies the event to the visual selection and exits visual
+// mode, and returns the mode to record the change in the history.
+func (w *window) operateVisual(e event.Event) mode.Mode {
+	if w.visualStart < 0 {
+		return mode.Normal
+	}
+	block := w.visualBlock
+	if block && (e.Type == event.OperatorChange || e.Type == event.Put) {
+		return mode.Visual
+	}
+	from, to := w.visualStart, w.cursor
+	if from > to {
+		from, to = to, from
+	}
+	w.exitVisual()
+	if block {
+		w.operateBlock(e, from, to)
+		return mode.Normal
+	}
+	if w.length == 0 {
+		if e.Type == event.OperatorChange {
+			w.startInsert()
+			return mode.Insert
+		}
+		return mode.Normal
+	}
+	switch e.Type {
+	case event.ReplaceSelection:
+		b, err := strconv.ParseUint(e.Arg, 16, 8)
+		if err != nil {
+			return mode.Normal
+		}
+		w.mapBytes(from, to, func(byte) byte { return byte(b) })
+		w.cursorGotoOffset(from)
+	case event.Increment, event.Decrement:
+		d := byte(mathutil.MaxInt64(e.Count, 1) % 256)
+		if e.Type == event.Decrement {
+			d = -d
+		}
+		w.mapBytes(from, to, func(b byte) byte { return b + d })
+		w.cursorGotoOffset(from)
+	case event.Put:
+		w.put(e.Rune, from, to)
+	default:
+		w.applyOperator(e.Type, e.Rune, from, to)
+	}
+	if e.Type == event.OperatorChange {
+		return mode.Insert
+	}
+	return mode.Normal
+}
+
+// mapBytes replaces the bytes from the offset to the end offset (inclusive)
+// with the function. The bytes are replaced in chunks.
+func (w *window) mapBytes(from, to int64, f func(byte) byte) {
+	for offset := from; offset <= to; offset += operatorChunk {
+		n, bytes, err := w.readBytes(offset, int(mathutil.MinInt64(operatorChunk, to-offset+1)))
+		if err != nil {
+			break
+		}
+		bytes = bytes[:n]
+		for i, b := range bytes {
+			bytes[i] = f(b)
+		}
+		w.buffer.DeleteRange(offset, offset+int64(n))
+		w.buffer.InsertBytes(offset, bytes)
+	}
+	w.changedTick++
+}
+
+// put replaces the bytes from the offset to the end offset (inclusive) with
+// the bytes of the register, and the unnamed register holds the replaced bytes.
+func (w *window) put(register rune, from, to int64) {
+	bytes := w.getRegister(register)
+	if len(bytes) == 0 {
+		return
+	}
+	w.applyOperator(event.OperatorDelete, 0, from, to)
+	w.buffer.InsertBytes(from, bytes)
+	w.length += int64(len(bytes))
+	w.changedTick++
+	w.cursorGotoOffset(from + int64(len(bytes)) - 1)
+}
+
+// putBytes inserts the bytes of the register count times after the cursor, or
+// before the cursor, and moves the cursor to the last inserted byte.
+func (w *window) putBytes(register rune, count int64, before bool) {
+	bytes := w.getRegister(register)
+	if len(bytes) == 0 {
+		return
+	}
+	offset := w.cursor
+	if !before && offset < w.length {
+		offset++
+	}
+	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
+		w.buffer.InsertBytes(offset, bytes)
+		offset += int64(len(bytes))
+		w.length += int64(len(bytes))
+	}
+	w.changedTick++
+	w.cursorGotoOffset(offset - 1)
+}
+
+// getRegister returns the bytes of the register, or the unnamed register.
+func (w *window) getRegister(register rune) []byte {
+	if w.registers == nil {
+		return nil
+	}
+	if register == 0 {
+		register = '"'
+	}
+	bytes, _ := w.registers.Get(register)
+	return bytes
+}
+
+// operateBlock applies the event to the slices of the rows in the blockwise
+// selection. Deleting the block fills the bytes with zeros, so that the
+// columns of the following rows are kept.
+func (w *window) operateBlock(e event.Event, from, to int64) {
+	left := mathutil.MinInt64(from%w.width, to%w.width)
+	right := mathutil.MaxInt64(from%w.width, to%w.width)
+	var b byte
+	switch e.Type {
+	case event.ReplaceSelection:
+		c, err := strconv.ParseUint(e.Arg, 16, 8)
+		if err != nil {
+			return
+		}
+		b = byte(c)
+	case event.Increment:
+		b = byte(mathutil.MaxInt64(e.Count, 1) % 256)
+	case event.Decrement:
+		b = -byte(mathutil.MaxInt64(e.Count, 1) % 256)
+	}
+	var yanked []byte
+	for row := from / w.width; row <= to/w.width; row++ {
+		i, j := row*w.width+left, mathutil.MinInt64(row*w.width+right, w.length-1)
+		if i > j {
+			continue
+		}
+		_, bytes, err := w.readBytes(i, int(j-i+1))
+		if err != nil {
+			return
+		}
+		yanked = append(yanked, bytes...)
+		for k, c := range bytes {
+			switch e.Type {
+			case event.OperatorDelete:
+				c = 0
+			case event.ReplaceSelection:
+				c = b
+			case event.Increment, event.Decrement:
+				c += b
+			case event.OperatorSwitchCase:
+				if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+					c ^= 0x20
+				}
+			}
+			if c != bytes[k] {
+				w.replace(i+int64(k), c)
+			}
+		}
+	}
+	switch e.Type {
+	case event.OperatorYank:
+		w.setRegisters(yanked, e.Rune, '0')
+	case event.OperatorDelete:
+		w.setRegisters(yanked, e.Rune, '1')
+	}
+	w.cursorGotoOffset(from/w.width*w.width + left)
+}
@@ -0,0 +1,53 @@
+package window
+
+import (
+	"strings"
+
+	"github.com/itchyny/bed/charset"
+)
+
+// setOption sets the window-local option. The value is validated by the
+// option package and has the type of the option.
+func (w *window) setOption(name string, value interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch name {
+	case "base":
+		w.base = value.(int64)
+	case "columns":
+		w.columns = value.(int64) // 0 means automatic
+	case "display":
+		w.display, w.pending, w.bitColumn = lookupDisplay(value.(string)), false, 0
+	case "encoding":
+		w.encoding = charset.Lookup(strings.ToLower(value.(string)))
+	case "follow":
+		w.setFollow(value.(bool))
+	case "group":
+		w.group = int(value.(int64))
+	case "offsetformat":
+		w.offsetFmt = value.(string)
+	}
+}
+
+// getOption returns the value of the window-local option.
+func (w *window) getOption(name string) interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch name {
+	case "base":
+		return w.base
+	case "columns":
+		return w.columns
+	case "display":
+		return w.display.name
+	case "encoding":
+		return w.encoding.Name
+	case "follow":
+		return w.followStop != nil
+	case "group":
+		return int64(w.group)
+	case "offsetformat":
+		return w.offsetFmt
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package window
+
+import (
+	"github.com/itchyny/bed/mathutil"
+	"github.com/itchyny/bed/state"
+)
+
+type paneKind int
+
+const (
+	paneNone paneKind = iota
+	paneInspector
+	paneTemplate
+	paneFormat
+	paneAnnotations
+)
+
+func (w *window) togglePane(pane paneKind) {
+	if w.pane == pane {
+		w.pane = paneNone
+	} else {
+		w.pane = pane
+	}
+}
+
+func (w *window) paneLen() int {
+	switch w.pane {
+	case paneInspector:
+		return len(inspectorRows)
+	case paneTemplate:
+		return len(w.template.lines)
+	case paneFormat:
+		return len(w.format.Entries)
+	case paneAnnotations:
+		return len(w.annotations)
+	default:
+		return 0
+	}
+}
+
+// paneHeight returns the height of the pane including the title line.
+func (w *window) paneHeight(height int) int {
+	n := w.paneLen()
+	if n == 0 {
+		return 0
+	}
+	if h := mathutil.MinInt(n+1, height/2); h >= 2 {
+		return h
+	}
+	return 0
+}
+
+// paneRange returns the range of the lines to show the line at the index
+// in the middle of the pane.
+func paneRange(index, n, height int) (int, int) {
+	start := mathutil.MaxInt(mathutil.MinInt(index-height/2, n-height), 0)
+	return start, mathutil.MinInt(start+height, n)
+}
+
+func (w *window) paneState() (*state.Pane, error) {
+	if w.paneLines == 0 {
+		return nil, nil
+	}
+	switch w.pane {
+	case paneInspector:
+		bs, err := w.inspectorBytes()
+		if err != nil {
+			return nil, err
+		}
+		return &state.Pane{Title: inspectorTitle, Lines: inspect(bs)[:w.paneLines-1], Index: -1}, nil
+	case paneTemplate:
+		w.evalTemplate()
+		return w.templatePane(w.paneLines - 1), nil
+	case paneFormat:
+		return w.formatPane(w.paneLines - 1), nil
+	case paneAnnotations:
+		return w.annotationPane(w.paneLines - 1), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (w *window) inspectorBytes() ([]byte, error) {
+	offset, size := w.cursor, int64(inspectorSize)
+	if w.visualStart >= 0 {
+		offset = mathutil.MinInt64(w.visualStart, w.cursor)
+		size = mathutil.MinInt64(mathutil.MaxInt64(w.visualStart, w.cursor)-offset+1, size)
+	}
+	n, bytes, err := w.readBytes(offset, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return bytes[:n], nil
+}
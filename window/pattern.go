@@ -0,0 +1,27 @@
+package window
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parsePattern parses a text pattern (/text/) or a hex pattern (0xcafe babe).
+func parsePattern(str string) ([]byte, error) {
+	var pattern []byte
+	if strings.HasPrefix(str, "0x") {
+		var err error
+		if pattern, err = hex.DecodeString(strings.Join(strings.Fields(str[2:]), "")); err != nil {
+			return nil, fmt.Errorf("invalid hex pattern: %s", str)
+		}
+	} else if strings.HasPrefix(str, "/") {
+		pattern = []byte(strings.TrimSuffix(str[1:], "/"))
+	} else {
+		pattern = []byte(str)
+	}
+	if len(pattern) == 0 {
+		return nil, errors.New("empty pattern")
+	}
+	return pattern, nil
+}
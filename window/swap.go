@@ -0,0 +1,211 @@
+package window
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/event"
+)
+
+// swapInterval is the delay to write the swap file after the buffer changes.
+var swapInterval = 4 * time.Second
+
+// The swap file consists of the magic, the size and the modification time of
+// the file when the swap file is written, and the journal of the buffer.
+const swapMagic = "bed-swap\x01"
+
+func swapPath(filename string) string {
+	return filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".bed.swp")
+}
+
+// scheduleSwap schedules writing the swap file after the buffer is changed.
+// The caller should hold the lock of the window.
+func (w *window) scheduleSwap() {
+	if w.swapName != "" && w.swapTimer == nil {
+		w.swapTimer = time.AfterFunc(swapInterval, w.writeSwap)
+	}
+}
+
+// writeSwap writes the swap file, or removes it if the buffer is not modified.
+// The journal is written from a clone of the buffer without holding the lock
+// of the window. The error is reported once until the swap file is written.
+func (w *window) writeSwap() {
+	w.mu.Lock()
+	w.swapTimer = nil
+	if w.swapName == "" {
+		w.mu.Unlock()
+		return
+	}
+	if w.changedTick == w.savedTick {
+		os.Remove(w.swapName)
+		w.mu.Unlock()
+		return
+	}
+	b, r, tick := w.buffer.Clone(), w.reader, w.changedTick
+	w.mu.Unlock()
+	name, err := writeSwapFile(w.swapName, b, r)
+	if name != "" {
+		defer os.Remove(name)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		// the buffer may be saved or the newer swap file may be written
+		if w.swapName == "" || w.changedTick == w.savedTick || tick < w.swapTick {
+			return
+		}
+		if err = os.Rename(name, w.swapName); err == nil {
+			w.swapTick, w.swapFailed = tick, false
+			return
+		}
+	}
+	if !w.swapFailed && w.messageCh != nil {
+		messageCh, err := w.messageCh, fmt.Errorf(
+			"%s: failed to write the swap file: %s", filepath.Base(w.swapName), err)
+		go func() { messageCh <- event.Event{Type: event.Error, Error: err} }()
+	}
+	w.swapFailed = true
+}
+
+// writeSwapFile writes the journal of the buffer to a temporary file next to
+// the swap file, and returns the name of the temporary file.
+func writeSwapFile(swapName string, b *buffer.Buffer, r readAtSeeker) (string, error) {
+	size, modTime := baseInfo(r)
+	f, err := os.OpenFile(
+		swapName+"-"+strconv.FormatUint(rand.Uint64(), 16),
+		os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600,
+	)
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	bw.WriteString(swapMagic)
+	buf := make([]byte, binary.MaxVarintLen64)
+	bw.Write(buf[:binary.PutVarint(buf, size)])
+	bw.Write(buf[:binary.PutVarint(buf, modTime)])
+	if err = bw.Flush(); err == nil {
+		err = b.WriteJournal(f, r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return f.Name(), err
+}
+
+// removeSwap stops writing the swap file and removes it. The caller should
+// hold the lock of the window.
+func (w *window) removeSwap() {
+	if w.swapTimer != nil {
+		w.swapTimer.Stop()
+		w.swapTimer = nil
+	}
+	if w.swapName != "" {
+		os.Remove(w.swapName)
+	}
+}
+
+// recoverSwap restores the buffer from the swap file, and returns the files
+// the journal refers to. The files should not be changed after the swap file
+// is written, because the journal refers to the offsets of the files.
+func (w *window) recoverSwap() (_ []*os.File, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var files []*os.File
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+		}
+	}()
+	f, err := os.Open(w.swapName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	magic := make([]byte, len(swapMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != swapMagic {
+		return nil, fmt.Errorf("%s: invalid swap file", filepath.Base(w.swapName))
+	}
+	size, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid swap file", filepath.Base(w.swapName))
+	}
+	modTime, err := binary.ReadVarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid swap file", filepath.Base(w.swapName))
+	}
+	if s, t := baseInfo(w.reader); s != size || t != modTime {
+		return nil, fmt.Errorf("%s: the file is changed after the swap file is written", w.name)
+	}
+	b, err := buffer.ReadJournal(br, w.reader, func(name string, size, modTime int64) (*os.File, error) {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		if s, t := baseInfo(f); s != size || t != modTime {
+			return nil, fmt.Errorf("%s: the file is changed after the swap file is written", name)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	length, err := b.Len()
+	if err != nil {
+		return nil, err
+	}
+	w.buffer, w.length = b, length
+	w.history.Push(w.buffer, 0, 0)
+	w.changedTick++
+	return files, nil
+}
+
+// rebase makes the buffer read the file which the buffer is written to. The
+// length of the file should be the same as the buffer.
+func (w *window) rebase(r readAtSeeker) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := buffer.NewBuffer(r)
+	length, err := b.Len()
+	if err != nil {
+		return err
+	}
+	if length != w.length {
+		return fmt.Errorf("%s: the file is changed after writing", w.name)
+	}
+	w.buffer, w.reader = b, r
+	return nil
+}
+
+// uses reports whether the buffer or the history of the window reads the
+// reader, so that the file can be closed otherwise.
+func (w *window) uses(r readAtSeeker) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reader == r || w.buffer.Uses(r) || w.history.Uses(r)
+}
+
+// baseInfo returns the size and the modification time of the file the window
+// is opened with. The reader is not a file for a new file.
+func baseInfo(r readAtSeeker) (int64, int64) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return 0, 0
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0
+	}
+	return info.Size(), info.ModTime().UnixNano()
+}
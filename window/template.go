@@ -0,0 +1,166 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/state"
+	"github.com/itchyny/bed/template"
+)
+
+type templateState struct {
+	template    *template.Template
+	name        string
+	offset      int64
+	field       *template.Field
+	lines       []templateLine
+	buffer      *buffer.Buffer
+	changedTick uint64
+}
+
+type templateLine struct {
+	field *template.Field
+	depth int
+}
+
+// parseTemplateArg splits the argument of :template to the file name and
+// the optional offset at the end.
+func parseTemplateArg(arg string) (string, event.Position) {
+	xs := strings.Fields(arg)
+	if len(xs) < 2 {
+		return arg, nil
+	}
+	last := []rune(xs[len(xs)-1])
+	if pos, i := event.ParsePos(last, 0); pos != nil && i == len(last) {
+		return strings.TrimSpace(arg[:strings.LastIndex(arg, xs[len(xs)-1])]), pos
+	}
+	return arg, nil
+}
+
+func readTemplate(name string) (*template.Template, error) {
+	name, err := homedir.Expand(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := template.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath.Base(name), err)
+	}
+	return t, nil
+}
+
+func (w *window) applyTemplate(t *template.Template, name string, pos event.Position) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var offset int64
+	if pos != nil {
+		var err error
+		if offset, err = w.positionToOffset(pos); err != nil {
+			return err
+		}
+	}
+	w.template = &templateState{template: t, name: name, offset: offset}
+	w.pane = paneTemplate
+	return w.evalTemplate()
+}
+
+func (w *window) toggleTemplate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.template == nil {
+		return errors.New("no template applied")
+	}
+	w.togglePane(paneTemplate)
+	return nil
+}
+
+// evalTemplate applies the template to the buffer again if it has changed.
+func (w *window) evalTemplate() error {
+	s := w.template
+	if s.field != nil && s.buffer == w.buffer && s.changedTick == w.changedTick {
+		return nil
+	}
+	field, err := s.template.Apply(w.buffer, s.offset)
+	s.field, s.buffer, s.changedTick, s.lines = field, w.buffer, w.changedTick, nil
+	field.Walk(func(f *template.Field, depth int) {
+		if depth > 0 {
+			s.lines = append(s.lines, templateLine{f, depth - 1})
+		}
+	})
+	return err
+}
+
+func (w *window) gotoField(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.template == nil {
+		return errors.New("no template applied")
+	}
+	w.evalTemplate()
+	f := w.template.field.Lookup(path)
+	if f == nil {
+		return fmt.Errorf("field not found: %s", path)
+	}
+	w.cursorGotoOffset(f.Offset)
+	return nil
+}
+
+func (w *window) templatePane(height int) *state.Pane {
+	s := w.template
+	index := -1
+	for i, l := range s.lines {
+		if l.field.Offset <= w.cursor && w.cursor < l.field.Offset+l.field.Size {
+			index = i
+		}
+	}
+	start, end := paneRange(index, len(s.lines), height)
+	lines := make([]string, 0, end-start)
+	for _, l := range s.lines[start:end] {
+		line := fmt.Sprintf("%08x %s%s: %s", l.field.Offset,
+			strings.Repeat("  ", l.depth), l.field.Name, l.field.Type)
+		if l.field.Value != "" {
+			line += " = " + l.field.Value
+		}
+		lines = append(lines, line)
+	}
+	if index >= 0 {
+		index -= start
+	}
+	return &state.Pane{
+		Title: fmt.Sprintf("template %s at 0x%x", s.name, s.offset),
+		Lines: lines, Index: index,
+	}
+}
+
+// templateHighlights returns the ranges of the fields in the view.
+// The color index changes field by field.
+func (w *window) templateHighlights() []state.Highlight {
+	var highlights []state.Highlight
+	from, to := w.offset, w.offset+w.height*w.width
+	var color int
+	for _, l := range w.template.lines {
+		f := l.field
+		if len(f.Children) > 0 || f.Size == 0 {
+			continue
+		}
+		if from < f.Offset+f.Size && f.Offset < to {
+			highlights = append(highlights, state.Highlight{
+				From: f.Offset, To: f.Offset + f.Size, Color: color,
+			})
+		}
+		color++
+	}
+	return highlights
+}
@@ -1,27 +1,39 @@
 package window
 
 import (
-	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/itchyny/bed/buffer"
+	"github.com/itchyny/bed/charset"
 	"github.com/itchyny/bed/event"
+	"github.com/itchyny/bed/format"
 	"github.com/itchyny/bed/history"
 	"github.com/itchyny/bed/mathutil"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/register"
+	"github.com/itchyny/bed/searcher"
 	"github.com/itchyny/bed/state"
 )
 
 type window struct {
 	buffer      *buffer.Buffer
+	reader      readAtSeeker
 	changedTick uint64
+	savedTick   uint64
 	prevChanged bool
 	history     *history.History
 	filename    string
+	fileInfo    os.FileInfo
+	warned      bool
 	name        string
 	height      int64
 	width       int64
@@ -34,10 +46,45 @@ type window struct {
 	extending   bool
 	pending     bool
 	pendingByte byte
+	pendingText string
+	pendingNum  int
+	bitColumn   int
 	visualStart int64
+	lastVisual  [2]int64
+	visualBlock bool
+	lastBlock   bool
+	operator    event.Type
+	opCount     int64
+	opRegister  rune
+	registers   *register.Registers
 	focusText   bool
+	matches     []int64
+	matchIndex  int
+	matchTick   uint64
+	findStop    chan struct{}
+	pane        paneKind
+	paneLines   int
+	template    *templateState
+	format      *format.Format
+	marks       map[rune]int64
+	annotations []annotation
+	columns     int64
+	group       int
+	display     *display
+	encoding    *charset.Charset
+	base        int64
+	offsetFmt   string
+	followStop  chan struct{}
+	followWg    *sync.WaitGroup
+	swapName    string
+	swapTimer   *time.Timer
+	swapFailed  bool
+	swapTick    uint64
+	messageCh   chan<- event.Event
 	redrawCh    chan<- struct{}
 	eventCh     chan event.Event
+	operated    chan mode.Mode
+	done        chan struct{}
 	mu          *sync.Mutex
 }
 
@@ -61,13 +108,22 @@ func newWindow(r readAtSeeker, filename string, name string, redrawCh chan<- str
 	history.Push(buffer, 0, 0)
 	return &window{
 		buffer:      buffer,
+		reader:      r,
 		history:     history,
 		filename:    filename,
 		name:        name,
 		length:      length,
 		visualStart: -1,
+		lastVisual:  [2]int64{-1, -1},
+		group:       1,
+		display:     displays[0],
+		encoding:    charset.Lookup("ascii"),
+		offsetFmt:   "hex",
 		redrawCh:    redrawCh,
+		followWg:    new(sync.WaitGroup),
 		eventCh:     make(chan event.Event),
+		operated:    make(chan mode.Mode, 1),
+		done:        make(chan struct{}),
 		mu:          new(sync.Mutex),
 	}, nil
 }
@@ -75,7 +131,11 @@ func newWindow(r readAtSeeker, filename string, name string, redrawCh chan<- str
 func (w *window) setSize(width, height int) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	w.width, w.height = int64(width), int64(height)
+	w.paneLines = w.paneHeight(height)
+	w.width, w.height = int64(width), int64(height-w.paneLines)
+	if w.columns > 0 {
+		w.width = w.columns
+	}
 	w.offset = w.offset / w.width * w.width
 	if w.cursor >= w.offset+w.height*w.width {
 		w.offset = (w.cursor - w.height*w.width + w.width) / w.width * w.width
@@ -90,123 +150,172 @@ func (w *window) run() {
 	for e := range w.eventCh {
 		w.mu.Lock()
 		offset, cursor, changedTick := w.offset, w.cursor, w.changedTick
-		switch e.Type {
-		case event.CursorUp:
-			w.cursorUp(e.Count)
-		case event.CursorDown:
-			w.cursorDown(e.Count)
-		case event.CursorLeft:
-			w.cursorLeft(e.Count)
-		case event.CursorRight:
-			w.cursorRight(e.Mode, e.Count)
-		case event.CursorPrev:
-			w.cursorPrev(e.Count)
-		case event.CursorNext:
-			w.cursorNext(e.Mode, e.Count)
-		case event.CursorHead:
-			w.cursorHead(e.Count)
-		case event.CursorEnd:
-			w.cursorEnd(e.Count)
-		case event.CursorGoto:
-			w.cursorGoto(e)
-		case event.ScrollUp:
-			w.scrollUp(e.Count)
-		case event.ScrollDown:
-			w.scrollDown(e.Count)
-		case event.PageUp:
-			w.pageUp()
-		case event.PageDown:
-			w.pageDown()
-		case event.PageUpHalf:
-			w.pageUpHalf()
-		case event.PageDownHalf:
-			w.pageDownHalf()
-		case event.PageTop:
-			w.pageTop()
-		case event.PageEnd:
-			w.pageEnd()
-		case event.JumpTo:
-			w.jumpTo()
-		case event.JumpBack:
-			w.jumpBack()
-
-		case event.DeleteByte:
-			w.deleteByte(e.Count)
-		case event.DeletePrevByte:
-			w.deletePrevByte(e.Count)
-		case event.Increment:
-			w.increment(e.Count)
-		case event.Decrement:
-			w.decrement(e.Count)
-
-		case event.StartInsert:
-			w.startInsert()
-		case event.StartInsertHead:
-			w.startInsertHead()
-		case event.StartAppend:
-			w.startAppend()
-		case event.StartAppendEnd:
-			w.startAppendEnd()
-		case event.StartReplaceByte:
-			w.startReplaceByte()
-		case event.StartReplace:
-			w.startReplace()
-		case event.ExitInsert:
-			w.exitInsert()
-		case event.Rune:
-			w.insertRune(e.Mode, e.Rune)
-		case event.Backspace:
-			w.backspace()
-		case event.Delete:
-			w.deleteByte(1)
-		case event.StartVisual:
-			w.startVisual()
-		case event.SwitchVisualEnd:
-			w.switchVisualEnd()
-		case event.ExitVisual:
+		if w.visualStart >= 0 && e.Mode != mode.Visual {
+			// the selection is left after the command line in visual mode
 			w.exitVisual()
-		case event.SwitchFocus:
-			w.focusText = !w.focusText
-			if w.pending {
-				w.pending = false
-				w.pendingByte = '\x00'
-			}
-			w.changedTick++
-		case event.Undo:
-			if e.Mode != mode.Normal {
-				panic("event.Undo should be emitted under normal mode")
-			}
-			w.undo(e.Count)
-		case event.Redo:
-			if e.Mode != mode.Normal {
-				panic("event.Undo should be emitted under normal mode")
+		}
+		m := e.Mode
+		if e.Mode == mode.OperatorPending {
+			m = w.operate(e)
+			w.operated <- m
+		} else if e.Mode == mode.Visual && event.IsVisualOperation(e.Type) {
+			m = w.operateVisual(e)
+		} else if !w.motion(e) {
+			switch e.Type {
+			case event.DeleteByte:
+				w.deleteByte(e.Count)
+			case event.DeletePrevByte:
+				w.deletePrevByte(e.Count)
+			case event.Increment:
+				w.increment(e.Count)
+			case event.Decrement:
+				w.decrement(e.Count)
+			case event.ToggleBit:
+				w.toggleBit(e.Count)
+
+			case event.StartInsert:
+				w.startInsert()
+			case event.StartInsertHead:
+				w.startInsertHead()
+			case event.StartAppend:
+				w.startAppend()
+			case event.StartAppendEnd:
+				w.startAppendEnd()
+			case event.StartReplaceByte:
+				w.startReplaceByte()
+			case event.StartReplace:
+				w.startReplace()
+			case event.ExitInsert:
+				w.exitInsert()
+			case event.Rune:
+				w.insertRune(e.Mode, e.Rune)
+			case event.Backspace:
+				w.backspace()
+			case event.Delete:
+				w.deleteByte(1)
+			case event.StartVisual:
+				w.startVisual()
+			case event.StartVisualBlock:
+				w.startVisualBlock()
+			case event.SwitchVisualEnd:
+				w.switchVisualEnd()
+			case event.ExitVisual:
+				w.exitVisual()
+			case event.ReselectVisual:
+				w.reselectVisual()
+			case event.ToggleInspector:
+				w.togglePane(paneInspector)
+			case event.SetMark:
+				w.setMark(e.Rune)
+			case event.SwitchFocus:
+				w.focusText = !w.focusText
+				if w.pending {
+					w.pending = false
+					w.pendingByte = '\x00'
+				}
+			case event.Undo:
+				if e.Mode != mode.Normal {
+					panic("event.Undo should be emitted under normal mode")
+				}
+				w.undo(e.Count)
+			case event.Redo:
+				if e.Mode != mode.Normal {
+					panic("event.Undo should be emitted under normal mode")
+				}
+				w.redo(e.Count)
+			case event.OperatorDelete, event.OperatorYank, event.OperatorChange, event.OperatorSwitchCase:
+				w.operator, w.opCount, w.opRegister = e.Type, e.Count, e.Rune
+			case event.CancelOperator:
+				w.operator, w.opCount, w.opRegister = event.Nop, 0, 0
+			case event.Put, event.PutBefore:
+				w.putBytes(e.Rune, e.Count, e.Type == event.PutBefore)
+			default:
+				w.mu.Unlock()
+				continue
 			}
-			w.redo(e.Count)
-		case event.ExecuteSearch:
-			w.search(e.Arg, e.Rune == '/')
-		case event.NextSearch:
-			w.search(e.Arg, e.Rune == '/')
-		case event.PreviousSearch:
-			w.search(e.Arg, e.Rune != '/')
-		default:
-			w.mu.Unlock()
-			continue
 		}
 		changed := changedTick != w.changedTick
 		if e.Type != event.Undo && e.Type != event.Redo {
-			if e.Mode == mode.Normal && changed || e.Type == event.ExitInsert && w.prevChanged {
+			if m == mode.Normal && changed || e.Type == event.ExitInsert && w.prevChanged {
 				w.history.Push(w.buffer, w.offset, w.cursor)
-			} else if e.Mode != mode.Normal && w.prevChanged && !changed &&
+			} else if m != mode.Normal && w.prevChanged && !changed &&
 				event.CursorUp <= e.Type && e.Type <= event.JumpBack {
 				w.history.Push(w.buffer, offset, cursor)
 			}
 		}
-		w.prevChanged = changed
+		// switching the focus does not change the buffer, but the changes
+		// before it are pushed to the history on exiting insert mode
+		if e.Type != event.SwitchFocus {
+			w.prevChanged = changed
+		}
+		if changed {
+			w.scheduleSwap()
+		}
 		w.mu.Unlock()
 		w.redrawCh <- struct{}{}
 	}
 }
 
+// motion moves the cursor by the motion event, and reports whether the event
+// is a motion.
+func (w *window) motion(e event.Event) bool {
+	switch e.Type {
+	case event.CursorUp:
+		w.cursorUp(e.Count)
+	case event.CursorDown:
+		w.cursorDown(e.Count)
+	case event.CursorLeft:
+		w.cursorLeft(e.Mode, e.Count)
+	case event.CursorRight:
+		w.cursorRight(e.Mode, e.Count)
+	case event.CursorPrev:
+		w.cursorPrev(e.Count)
+	case event.CursorNext:
+		w.cursorNext(e.Mode, e.Count)
+	case event.CursorHead:
+		w.cursorHead(e.Count)
+	case event.CursorEnd:
+		w.cursorEnd(e.Count)
+	case event.CursorGoto:
+		w.cursorGoto(e)
+	case event.ScrollUp:
+		w.scrollUp(e.Count)
+	case event.ScrollDown:
+		w.scrollDown(e.Count)
+	case event.PageUp:
+		w.pageUp()
+	case event.PageDown:
+		w.pageDown()
+	case event.PageUpHalf:
+		w.pageUpHalf()
+	case event.PageDownHalf:
+		w.pageDownHalf()
+	case event.PageTop:
+		w.pageTop()
+	case event.PageEnd:
+		w.pageEnd()
+	case event.JumpTo:
+		w.jumpTo()
+	case event.JumpBack:
+		w.jumpBack()
+	case event.FindByte:
+		w.findByte(e.Arg, e.Count, true)
+	case event.FindByteBackward:
+		w.findByte(e.Arg, e.Count, false)
+	case event.JumpMark:
+		_ = w.gotoMark(e.Rune)
+	case event.ExecuteSearch:
+		w.search(e.Arg, e.Rune == '/')
+	case event.NextSearch:
+		w.search(e.Arg, e.Rune == '/')
+	case event.PreviousSearch:
+		w.search(e.Arg, e.Rune != '/')
+	default:
+		return false
+	}
+	return true
+}
+
 func (w *window) readBytes(offset int64, len int) (int, []byte, error) {
 	bytes := make([]byte, len)
 	n, err := w.buffer.ReadAt(bytes, offset)
@@ -225,28 +334,40 @@ func (w *window) writeTo(r *event.Range, dst io.Writer) (int64, error) {
 		}
 		return io.Copy(dst, w.buffer)
 	}
+	from, to, err := w.rangeToOffsets(r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.buffer.Seek(from, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.Copy(dst, io.LimitReader(w.buffer, to-from+1))
+}
+
+func (w *window) rangeToOffsets(r *event.Range) (int64, int64, error) {
 	var from, to int64
 	var err error
 	if from, err = w.positionToOffset(r.From); err != nil {
-		return 0, err
+		return 0, 0, err
+	}
+	if r.To == nil {
+		return from, from, nil
 	}
 	if to, err = w.positionToOffset(r.To); err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	if from > to {
 		from, to = to, from
 	}
-	if _, err := w.buffer.Seek(from, io.SeekStart); err != nil {
-		return 0, err
-	}
-	return io.Copy(dst, io.LimitReader(w.buffer, to-from+1))
+	return from, to, nil
 }
 
 func (w *window) positionToOffset(pos event.Position) (int64, error) {
 	switch pos := pos.(type) {
 	case event.Absolute:
+		// absolute positions are addresses shifted by the base
 		return mathutil.MaxInt64(
-			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1),
+			mathutil.MinInt64(pos.Offset-w.base, mathutil.MaxInt64(w.length, 1)-1),
 			0,
 		), nil
 	case event.Relative:
@@ -260,21 +381,32 @@ func (w *window) positionToOffset(pos event.Position) (int64, error) {
 			-(mathutil.MaxInt64(w.length, 1)-1),
 		), nil
 	case event.VisualStart:
-		if w.visualStart < 0 {
-			return 0, errors.New("no visual selection found")
+		start, _, err := w.visualRange()
+		if err != nil {
+			return 0, err
 		}
-		// TODO: save visualStart after exitting visual mode
-		return w.visualStart + mathutil.MaxInt64(
-			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-w.visualStart),
-			-w.visualStart,
+		return start + mathutil.MaxInt64(
+			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-start),
+			-start,
 		), nil
 	case event.VisualEnd:
-		if w.visualStart < 0 {
-			return 0, errors.New("no visual selection found")
+		_, end, err := w.visualRange()
+		if err != nil {
+			return 0, err
 		}
-		return w.cursor + mathutil.MaxInt64(
-			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-w.cursor),
-			-w.cursor,
+		return end + mathutil.MaxInt64(
+			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-end),
+			-end,
+		), nil
+	case event.Mark:
+		offset, ok := w.marks[pos.Name]
+		if !ok {
+			return 0, fmt.Errorf("mark not set: %c", pos.Name)
+		}
+		offset = mathutil.MinInt64(offset, mathutil.MaxInt64(w.length, 1)-1)
+		return offset + mathutil.MaxInt64(
+			mathutil.MinInt64(pos.Offset, mathutil.MaxInt64(w.length, 1)-1-offset),
+			-offset,
 		), nil
 	default:
 		return 0, errors.New("invalid range")
@@ -288,8 +420,24 @@ func (w *window) state() (*state.WindowState, error) {
 	if err != nil {
 		return nil, err
 	}
+	pane, err := w.paneState()
+	if err != nil {
+		return nil, err
+	}
+	var formatName string
+	if w.format != nil {
+		formatName = w.format.Name
+	}
+	highlights := w.annotationHighlights()
+	if w.pane == paneTemplate {
+		highlights = append(highlights, w.templateHighlights()...)
+		sort.SliceStable(highlights, func(i, j int) bool {
+			return highlights[i].From < highlights[j].From
+		})
+	}
 	return &state.WindowState{
 		Name:          w.name,
+		Modified:      w.changedTick != w.savedTick,
 		Width:         int(w.width),
 		Offset:        w.offset,
 		Cursor:        w.cursor,
@@ -298,9 +446,20 @@ func (w *window) state() (*state.WindowState, error) {
 		Length:        w.length,
 		Pending:       w.pending,
 		PendingByte:   w.pendingByte,
+		PendingText:   w.pendingText,
+		BitColumn:     w.bitCursor(),
 		VisualStart:   w.visualStart,
+		VisualBlock:   w.visualBlock,
 		EditedIndices: w.buffer.EditedIndices(),
 		FocusText:     w.focusText,
+		Group:         w.group,
+		Display:       w.display.name,
+		Encoding:      w.encoding.Name,
+		Base:          w.base,
+		OffsetFormat:  w.offsetFmt,
+		Format:        formatName,
+		Pane:          pane,
+		Highlights:    highlights,
 	}, nil
 }
 
@@ -319,14 +478,20 @@ func (w *window) delete(offset int64) {
 	w.changedTick++
 }
 
+// undo restores the previous buffer in the history. The buffer is considered
+// changed, even if it is restored to the buffer written to the file.
 func (w *window) undo(count int64) {
 	for i := int64(0); i < mathutil.MaxInt64(count, 1); i++ {
+		if !w.history.Undoable() {
+			return
+		}
 		buffer, _, offset, cursor := w.history.Undo()
 		if buffer == nil {
 			return
 		}
 		w.buffer, w.offset, w.cursor = buffer, offset, cursor
 		w.length, _ = w.buffer.Len()
+		w.changedTick++
 	}
 }
 
@@ -338,6 +503,7 @@ func (w *window) redo(count int64) {
 		}
 		w.buffer, w.offset, w.cursor = buffer, offset, cursor
 		w.length, _ = w.buffer.Len()
+		w.changedTick++
 	}
 }
 
@@ -367,7 +533,13 @@ func (w *window) cursorDown(count int64) {
 	}
 }
 
-func (w *window) cursorLeft(count int64) {
+func (w *window) cursorLeft(m mode.Mode, count int64) {
+	if m == mode.Normal && w.bitwise() {
+		bits := w.cursor%w.width*8 + int64(w.bitColumn)
+		bits -= mathutil.MinInt64(mathutil.MaxInt64(count, 1), bits)
+		w.cursor, w.bitColumn = w.cursor-w.cursor%w.width+bits/8, int(bits%8)
+		return
+	}
 	w.cursor -= mathutil.MinInt64(mathutil.MaxInt64(count, 1), w.cursor%w.width)
 	if w.append && w.extending && w.cursor < w.length-1 {
 		w.append = false
@@ -379,7 +551,12 @@ func (w *window) cursorLeft(count int64) {
 }
 
 func (w *window) cursorRight(m mode.Mode, count int64) {
-	if m == mode.Normal {
+	if m == mode.Normal && w.bitwise() {
+		bits := mathutil.MinInt64(w.width-1-w.cursor%w.width,
+			mathutil.MaxInt64(w.length, 1)-1-w.cursor)*8 + 7 - int64(w.bitColumn)
+		bits = int64(w.bitColumn) + mathutil.MinInt64(mathutil.MaxInt64(count, 1), bits)
+		w.cursor, w.bitColumn = w.cursor+bits/8, int(bits%8)
+	} else if m == mode.Normal {
 		w.cursor += mathutil.MinInt64(
 			mathutil.MinInt64(mathutil.MaxInt64(count, 1), w.width-1-w.cursor%w.width),
 			mathutil.MaxInt64(w.length, 1)-1-w.cursor,
@@ -453,13 +630,17 @@ func (w *window) cursorGoto(e event.Event) {
 
 func (w *window) cursorGotoPos(pos event.Position) {
 	if offset, err := w.positionToOffset(pos); err == nil {
-		w.cursor = mathutil.MaxInt64(mathutil.MinInt64(offset, mathutil.MaxInt64(w.length, 1)-1), 0)
-		if w.cursor < w.offset {
-			w.offset = (mathutil.MaxInt64(w.cursor/w.width, w.height/2) - w.height/2) * w.width
-		} else if w.cursor >= w.offset+w.height*w.width {
-			h := (mathutil.MaxInt64(w.length, 1)+w.width-1)/w.width - w.height
-			w.offset = mathutil.MinInt64((w.cursor-w.height*w.width+w.width)/w.width+w.height/2, h) * w.width
-		}
+		w.cursorGotoOffset(offset)
+	}
+}
+
+func (w *window) cursorGotoOffset(offset int64) {
+	w.cursor = mathutil.MaxInt64(mathutil.MinInt64(offset, mathutil.MaxInt64(w.length, 1)-1), 0)
+	if w.cursor < w.offset {
+		w.offset = (mathutil.MaxInt64(w.cursor/w.width, w.height/2) - w.height/2) * w.width
+	} else if w.cursor >= w.offset+w.height*w.width {
+		h := (mathutil.MaxInt64(w.length, 1)+w.width-1)/w.width - w.height
+		w.offset = mathutil.MinInt64((w.cursor-w.height*w.width+w.width)/w.width+w.height/2, h) * w.width
 	}
 }
 
@@ -702,49 +883,46 @@ func (w *window) insertRune(m mode.Mode, ch rune) {
 			buf := make([]byte, 4)
 			n := utf8.EncodeRune(buf, ch)
 			for i := 0; i < n; i++ {
-				w.insertByte(m, byte(buf[i]>>4))
-				w.insertByte(m, byte(buf[i]&0x0f))
+				w.putByte(m, buf[i])
 			}
-		} else if '0' <= ch && ch <= '9' {
-			w.insertByte(m, byte(ch-'0'))
-		} else if 'a' <= ch && ch <= 'f' {
-			w.insertByte(m, byte(ch-'a'+0x0a))
+		} else if m == mode.Replace && w.bitwise() && (ch == '0' || ch == '1') {
+			w.replaceBit(m, ch == '1')
+		} else if v, ok := w.display.digitValue(ch); ok {
+			w.insertByte(m, v)
+		} else if ch == '-' && w.display.signed && !w.pending {
+			w.pending, w.pendingText, w.pendingNum = true, "-", 0
 		}
 	}
 }
 
-func (w *window) insertByte(m mode.Mode, b byte) {
-	if w.pending {
-		switch m {
-		case mode.Insert:
-			w.insert(w.cursor, w.pendingByte|b)
-			w.cursor++
+func (w *window) putByte(m mode.Mode, b byte) {
+	switch m {
+	case mode.Insert:
+		w.insert(w.cursor, b)
+		w.cursor++
+		w.length++
+	case mode.Replace:
+		w.replace(w.cursor, b)
+		if w.length == 0 {
 			w.length++
-		case mode.Replace:
-			w.replace(w.cursor, w.pendingByte|b)
-			if w.length == 0 {
+		}
+		if w.replaceByte {
+			w.exitInsert()
+		} else {
+			w.cursor++
+			if w.cursor == w.length {
+				w.append = true
+				w.extending = true
 				w.length++
 			}
-			if w.replaceByte {
-				w.exitInsert()
-			} else {
-				w.cursor++
-				if w.cursor == w.length {
-					w.append = true
-					w.extending = true
-					w.length++
-				}
-			}
 		}
-		if w.cursor >= w.offset+w.height*w.width {
-			w.offset = (w.cursor - w.height*w.width + w.width) / w.width * w.width
-		}
-		w.pending = false
-		w.pendingByte = '\x00'
-	} else {
-		w.pending = true
-		w.pendingByte = b << 4
 	}
+	if w.cursor >= w.offset+w.height*w.width {
+		w.offset = (w.cursor - w.height*w.width + w.width) / w.width * w.width
+	}
+	w.pending = false
+	w.pendingByte = '\x00'
+	w.pendingText = ""
 }
 
 func (w *window) backspace() {
@@ -760,6 +938,16 @@ func (w *window) backspace() {
 
 func (w *window) startVisual() {
 	w.visualStart = w.cursor
+	w.visualBlock = false
+}
+
+// startVisualBlock starts blockwise visual mode, or switches the visual mode
+// to blockwise keeping the start of the selection.
+func (w *window) startVisualBlock() {
+	if w.visualStart < 0 {
+		w.visualStart = w.cursor
+	}
+	w.visualBlock = true
 }
 
 func (w *window) switchVisualEnd() {
@@ -775,7 +963,37 @@ func (w *window) switchVisualEnd() {
 }
 
 func (w *window) exitVisual() {
-	w.visualStart = -1
+	if w.visualStart >= 0 {
+		w.lastVisual = [2]int64{w.visualStart, w.cursor}
+		w.lastBlock = w.visualBlock
+	}
+	w.visualStart, w.visualBlock = -1, false
+}
+
+// reselectVisual starts visual mode with the last selection, or at the
+// cursor if there is no previous selection.
+func (w *window) reselectVisual() {
+	w.exitVisual()
+	if w.lastVisual[0] < 0 {
+		w.startVisual()
+		return
+	}
+	w.cursorGotoOffset(w.lastVisual[0])
+	w.visualStart, w.visualBlock = w.cursor, w.lastBlock
+	w.cursorGotoOffset(w.lastVisual[1])
+}
+
+// visualRange returns the start and end of the visual selection, or the
+// last selection after exiting visual mode.
+func (w *window) visualRange() (int64, int64, error) {
+	if w.visualStart >= 0 {
+		return w.visualStart, w.cursor, nil
+	}
+	if w.lastVisual[0] < 0 {
+		return 0, 0, errors.New("no visual selection found")
+	}
+	max := mathutil.MaxInt64(w.length, 1) - 1
+	return mathutil.MinInt64(w.lastVisual[0], max), mathutil.MinInt64(w.lastVisual[1], max), nil
 }
 
 func (w *window) search(str string, forward bool) {
@@ -789,13 +1007,12 @@ func (w *window) search(str string, forward bool) {
 func (w *window) searchForward(str string) {
 	target := []byte(str)
 	base, size := w.cursor+1, mathutil.MaxInt(int(w.height*w.width)*50, len(target)*500)
-	_, bs, err := w.readBytes(base, size)
+	i, err := searcher.NewSearcher(w.buffer, target).Forward(base, base+int64(size))
 	if err != nil {
 		return
 	}
-	i := bytes.Index(bs, target)
 	if i >= 0 {
-		w.cursor = base + int64(i)
+		w.cursor = i
 		if w.cursor >= w.offset+w.height*w.width {
 			w.offset = (w.cursor - w.height*w.width + w.width + 1) / w.width * w.width
 		}
@@ -806,19 +1023,222 @@ func (w *window) searchBackward(str string) {
 	target := []byte(str)
 	size := mathutil.MaxInt(int(w.height*w.width)*50, len(target)*500)
 	base := mathutil.MaxInt64(0, w.cursor-int64(size))
-	_, bs, err := w.readBytes(base, int(mathutil.MinInt64(int64(size), w.cursor)))
+	i, err := searcher.NewSearcher(w.buffer, target).Backward(base, w.cursor)
 	if err != nil {
 		return
 	}
-	i := bytes.LastIndex(bs, target)
 	if i >= 0 {
-		w.cursor = base + int64(i)
+		w.cursor = i
 		if w.cursor < w.offset {
 			w.offset = w.cursor / w.width * w.width
 		}
 	}
 }
 
+func (w *window) snapshot(r *event.Range) (*buffer.Buffer, int64, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if r == nil {
+		return w.buffer.Clone(), 0, w.length, nil
+	}
+	from, to, err := w.rangeToOffsets(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return w.buffer.Clone(), from, to + 1, nil
+}
+
+// rename changes the file name of the window, and moves the swap file and the
+// annotations to the new file name.
+func (w *window) rename(filename string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if filename == w.filename {
+		return nil
+	}
+	if err := saveAnnotations(filename, w.annotations); err != nil {
+		return err
+	}
+	if w.filename != "" {
+		if err := saveAnnotations(w.filename, nil); err != nil {
+			return err
+		}
+	}
+	if w.swapName != "" {
+		w.removeSwap()
+		w.swapName = swapPath(filename)
+	}
+	w.filename, w.name = filename, filepath.Base(filename)
+	return nil
+}
+
+// modified reports whether the buffer is changed since it was written.
+func (w *window) modified() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.changedTick != w.savedTick
+}
+
+// setSaved marks the buffer as written to the file.
+func (w *window) setSaved() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.savedTick = w.changedTick
+	w.removeSwap()
+}
+
+// replaceBytes replaces the bytes from the offset to the end offset
+// (exclusive) with the bytes.
+func (w *window) replaceBytes(from, to int64, bs []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.replaceRange(from, to, bs)
+}
+
+// readFrom inserts the first n bytes of the reader at the offset of the
+// range, or the cursor, and returns the number of bytes. The end of the buffer
+// ($) means appending the bytes. The bytes overwrite the buffer without
+// changing the length if replace is true.
+func (w *window) readFrom(r *event.Range, rd readAtSeeker, n int64, replace bool) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	offset := w.cursor
+	if r != nil {
+		var err error
+		if offset, _, err = w.rangeToOffsets(r); err != nil {
+			return 0, err
+		}
+		if pos, ok := r.From.(event.End); ok && pos.Offset >= 0 && !replace {
+			offset = w.length
+		}
+	}
+	if replace {
+		n = mathutil.MinInt64(n, w.length-offset)
+		w.buffer.ReplaceReader(offset, rd, n)
+	} else {
+		w.buffer.InsertReader(offset, rd, n)
+		w.length += n
+	}
+	if n > 0 {
+		w.changedTick++
+		w.scheduleSwap()
+		w.cursorGotoOffset(offset)
+		w.history.Push(w.buffer, w.offset, w.cursor)
+	}
+	return n, nil
+}
+
+func (w *window) replaceRange(from, to int64, bs []byte) {
+	to = mathutil.MinInt64(to, w.length)
+	w.buffer.DeleteRange(from, to)
+	w.buffer.InsertBytes(from, bs)
+	w.length += int64(len(bs)) - (to - from)
+	w.changedTick++
+	w.scheduleSwap()
+	w.cursorGotoOffset(from)
+	w.history.Push(w.buffer, w.offset, w.cursor)
+}
+
+var (
+	errFindStopped = errors.New("the scan is stopped")
+	errFindChanged = errors.New("the buffer is changed while finding the matches")
+)
+
+// startFind takes a snapshot of the buffer to find the matches in, and stops
+// the previous scan. The returned channel is closed when the scan should stop.
+func (w *window) startFind(r *event.Range) (*buffer.Buffer, int64, int64, chan struct{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	from, to := int64(0), w.length
+	if r != nil {
+		var err error
+		if from, to, err = w.rangeToOffsets(r); err != nil {
+			return nil, 0, 0, nil, err
+		}
+		to++
+	}
+	w.stopFind()
+	w.findStop = make(chan struct{})
+	w.matches, w.matchIndex, w.matchTick = nil, -1, w.changedTick
+	return w.buffer.Clone(), from, to, w.findStop, nil
+}
+
+// stopFind stops the running scan. The caller should hold the lock.
+func (w *window) stopFind() {
+	if w.findStop != nil {
+		close(w.findStop)
+		w.findStop = nil
+	}
+}
+
+// setMatches sets the matches found by the scan. The matches are discarded
+// if the buffer is changed during the scan, since the offsets are stale.
+func (w *window) setMatches(matches []int64, stop chan struct{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.findErr(stop); err != nil {
+		return err
+	}
+	w.findStop = nil
+	w.matches, w.matchIndex = matches, -1
+	return nil
+}
+
+// findInterrupted reports whether the scan should stop, because another scan
+// is started or the buffer is changed.
+func (w *window) findInterrupted(stop chan struct{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.findErr(stop)
+}
+
+func (w *window) findErr(stop chan struct{}) error {
+	select {
+	case <-stop:
+		return errFindStopped
+	default:
+	}
+	if w.changedTick != w.matchTick {
+		return errFindChanged
+	}
+	return nil
+}
+
+func (w *window) jumpMatch(count int64, forward bool) (int, int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.changedTick != w.matchTick {
+		// the offsets of the matches are not adjusted with the changes
+		w.matches, w.matchIndex = nil, -1
+	}
+	if len(w.matches) == 0 {
+		return 0, 0, errors.New("no matches")
+	}
+	if forward {
+		if w.matchIndex >= len(w.matches)-1 {
+			return 0, 0, errors.New("no more matches")
+		}
+		w.matchIndex = int(mathutil.MinInt64(
+			int64(w.matchIndex)+mathutil.MaxInt64(count, 1), int64(len(w.matches)-1)))
+	} else {
+		if w.matchIndex <= 0 {
+			return 0, 0, errors.New("no previous matches")
+		}
+		w.matchIndex = int(mathutil.MaxInt64(
+			int64(w.matchIndex)-mathutil.MaxInt64(count, 1), 0))
+	}
+	w.cursorGotoOffset(w.matches[w.matchIndex])
+	return w.matchIndex, len(w.matches), nil
+}
+
 func (w *window) close() {
+	w.mu.Lock()
+	close(w.done)
+	w.stopFind()
+	w.setFollow(false)
+	w.removeSwap()
+	w.swapName = ""
+	w.mu.Unlock()
+	w.followWg.Wait()
 	close(w.eventCh)
 }
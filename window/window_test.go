@@ -9,6 +9,7 @@ import (
 
 	"github.com/itchyny/bed/event"
 	"github.com/itchyny/bed/mode"
+	"github.com/itchyny/bed/register"
 )
 
 func TestWindowState(t *testing.T) {
@@ -189,13 +190,13 @@ func TestWindowCursorMotions(t *testing.T) {
 		t.Errorf("s.Cursor should be %d but got %d", width*12-1, s.Cursor)
 	}
 
-	window.cursorLeft(3)
+	window.cursorLeft(mode.Normal, 3)
 	s, _ = window.state()
 	if s.Cursor != int64(width)*12-4 {
 		t.Errorf("s.Cursor should be %d but got %d", width*12-4, s.Cursor)
 	}
 
-	window.cursorLeft(20)
+	window.cursorLeft(mode.Normal, 20)
 	s, _ = window.state()
 	if s.Cursor != int64(width)*11 {
 		t.Errorf("s.Cursor should be %d but got %d", width*11, s.Cursor)
@@ -1130,6 +1131,36 @@ func TestWindowEventRuneText(t *testing.T) {
 	window.close()
 }
 
+func TestWindowEventSwitchFocusUndo(t *testing.T) {
+	width, height := 16, 10
+	redrawCh := make(chan struct{})
+	window, _ := newWindow(strings.NewReader("Hello, world!"), "test", "test", redrawCh)
+	window.setSize(width, height)
+	go window.run()
+	go func() {
+		window.eventCh <- event.Event{Type: event.SwitchFocus, Mode: mode.Normal}
+		window.eventCh <- event.Event{Type: event.StartInsert, Mode: mode.Insert}
+		window.eventCh <- event.Event{Type: event.Rune, Rune: 'a', Mode: mode.Insert}
+		window.eventCh <- event.Event{Type: event.ExitInsert, Mode: mode.Normal}
+		window.eventCh <- event.Event{Type: event.StartInsert, Mode: mode.Insert}
+		window.eventCh <- event.Event{Type: event.Rune, Rune: 'x', Mode: mode.Insert}
+		window.eventCh <- event.Event{Type: event.SwitchFocus, Mode: mode.Insert}
+		window.eventCh <- event.Event{Type: event.ExitInsert, Mode: mode.Normal}
+		window.eventCh <- event.Event{Type: event.Undo, Mode: mode.Normal}
+	}()
+	for i := 0; i < 9; i++ {
+		<-redrawCh
+		if i == 0 && window.modified() {
+			t.Errorf("switching the focus should not modify the buffer")
+		}
+	}
+	s, _ := window.state()
+	if !strings.HasPrefix(string(s.Bytes), "aHello, world!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "aHello, world!\x00", string(s.Bytes))
+	}
+	window.close()
+}
+
 func TestWindowEventUndoRedo(t *testing.T) {
 	width, height := 16, 10
 	redrawCh := make(chan struct{})
@@ -1272,3 +1303,477 @@ func TestWindowWriteTo(t *testing.T) {
 		}
 	}
 }
+
+func TestWindowInspector(t *testing.T) {
+	r := strings.NewReader("\x01\x02\x03\x04\x05\x06\x07\x08")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	s, _ := window.state()
+	if s.Pane != nil {
+		t.Errorf("s.Pane should be nil but got %+v", s.Pane)
+	}
+
+	window.togglePane(paneInspector)
+	window.setSize(16, 10)
+	s, _ = window.state()
+	if s.Pane == nil {
+		t.Fatalf("s.Pane should not be nil")
+	}
+	if len(s.Pane.Lines) != 4 {
+		t.Errorf("len(s.Pane.Lines) should be %d but got %d", 4, len(s.Pane.Lines))
+	}
+	if window.height != 5 {
+		t.Errorf("window.height should be %d but got %d", 5, window.height)
+	}
+	expected := "int16      513                      258"
+	if s.Pane.Lines[2] != expected {
+		t.Errorf("s.Pane.Lines[2] should be %q but got %q", expected, s.Pane.Lines[2])
+	}
+
+	window.cursorNext(mode.Normal, 2)
+	window.startVisual()
+	window.cursorNext(mode.Normal, 1)
+	window.setSize(16, 40)
+	s, _ = window.state()
+	if len(s.Pane.Lines) != len(inspectorRows) {
+		t.Errorf("len(s.Pane.Lines) should be %d but got %d", len(inspectorRows), len(s.Pane.Lines))
+	}
+	for _, expected := range []string{
+		"int16      1027                     772",
+		"int32",
+	} {
+		if !contains(s.Pane.Lines, expected) {
+			t.Errorf("s.Pane.Lines should contain %q but got %q", expected, s.Pane.Lines)
+		}
+	}
+
+	window.togglePane(paneInspector)
+	window.setSize(16, 10)
+	s, _ = window.state()
+	if s.Pane != nil {
+		t.Errorf("s.Pane should be nil but got %+v", s.Pane)
+	}
+}
+
+func TestWindowMarks(t *testing.T) {
+	r := strings.NewReader(strings.Repeat("Hello, world!", 10))
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	if _, err := window.positionToOffset(event.Mark{Name: 'a'}); err == nil || err.Error() != "mark not set: a" {
+		t.Errorf("positionToOffset should return an error but got: %v", err)
+	}
+	window.cursorNext(mode.Normal, 20)
+	window.setMark('a')
+	window.cursorNext(mode.Normal, 40)
+	window.setMark('b')
+	from, to, err := window.rangeToOffsets(&event.Range{From: event.Mark{Name: 'b', Offset: 2}, To: event.Mark{Name: 'a'}})
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if from != 20 || to != 62 {
+		t.Errorf("rangeToOffsets should return 20 and 62 but got %d and %d", from, to)
+	}
+	if err := window.jumpMark('a'); err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if window.cursor != 20 {
+		t.Errorf("window.cursor should be %d but got %d", 20, window.cursor)
+	}
+	if err := window.jumpMark('c'); err == nil || err.Error() != "mark not set: c" {
+		t.Errorf("jumpMark should return an error but got: %v", err)
+	}
+}
+
+func TestWindowBase(t *testing.T) {
+	r := strings.NewReader(strings.Repeat("Hello, world!", 10))
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	window.setOption("base", int64(0x8000))
+	if base := window.getOption("base"); base != int64(0x8000) {
+		t.Errorf("base should be %d but got %v", 0x8000, base)
+	}
+	window.cursorGoto(event.Event{Range: &event.Range{From: event.Absolute{Offset: 0x8020}}})
+	if window.cursor != 0x20 {
+		t.Errorf("window.cursor should be %d but got %d", 0x20, window.cursor)
+	}
+	window.cursorGoto(event.Event{Range: &event.Range{From: event.Absolute{Offset: 0x20}}})
+	if window.cursor != 0 {
+		t.Errorf("window.cursor should be %d but got %d", 0, window.cursor)
+	}
+	from, to, err := window.rangeToOffsets(&event.Range{From: event.Absolute{Offset: 0x8010}, To: event.End{}})
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if from != 0x10 || to != 129 {
+		t.Errorf("rangeToOffsets should return %d and %d but got %d and %d", 0x10, 129, from, to)
+	}
+	s, err := window.state()
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if s.Base != 0x8000 || s.OffsetFormat != "hex" {
+		t.Errorf("base and offset format should be %d and %q but got %d and %q", 0x8000, "hex", s.Base, s.OffsetFormat)
+	}
+}
+
+func TestWindowDisplayInput(t *testing.T) {
+	for _, testCase := range []struct {
+		display  string
+		input    string
+		expected string
+		pending  string
+	}{
+		{"hex", "41g42c", "AB", "c"},
+		{"binary", "0100000120100001001", "AB", "01"},
+		{"octal", "10181024", "AB", "4"},
+		{"decimal", "65066255262", "AB\xff\x1a", "2"},
+		{"signed", "127-128-13-", "\x7f\x80\xf3", "-"},
+	} {
+		window, _ := newWindow(strings.NewReader(""), "test", "test", make(chan struct{}))
+		window.setSize(16, 10)
+		window.setOption("display", testCase.display)
+		window.startInsert()
+		for _, ch := range testCase.input {
+			window.insertRune(mode.Insert, ch)
+		}
+		s, _ := window.state()
+		if !strings.HasPrefix(string(s.Bytes), testCase.expected+"\x00") {
+			t.Errorf("s.Bytes should start with %q but got %q (%s)", testCase.expected, string(s.Bytes[:8]), testCase.display)
+		}
+		if !s.Pending || s.PendingText != testCase.pending {
+			t.Errorf("s.PendingText should be %q but got %q (%s)", testCase.pending, s.PendingText, testCase.display)
+		}
+		if s.Display != testCase.display {
+			t.Errorf("s.Display should be %q but got %q", testCase.display, s.Display)
+		}
+	}
+}
+
+func TestWindowDisplayBits(t *testing.T) {
+	window, _ := newWindow(strings.NewReader("AB"), "test", "test", make(chan struct{}))
+	window.setSize(16, 10)
+	window.setOption("display", "binary")
+
+	window.cursorRight(mode.Normal, 9)
+	if s, _ := window.state(); s.Cursor != 1 || s.BitColumn != 1 {
+		t.Errorf("s.Cursor and s.BitColumn should be 1 and 1 but got %d and %d", s.Cursor, s.BitColumn)
+	}
+	window.cursorRight(mode.Normal, 100)
+	if s, _ := window.state(); s.Cursor != 1 || s.BitColumn != 7 {
+		t.Errorf("s.Cursor and s.BitColumn should be 1 and 7 but got %d and %d", s.Cursor, s.BitColumn)
+	}
+	window.cursorLeft(mode.Normal, 10)
+	if s, _ := window.state(); s.Cursor != 0 || s.BitColumn != 5 {
+		t.Errorf("s.Cursor and s.BitColumn should be 0 and 5 but got %d and %d", s.Cursor, s.BitColumn)
+	}
+
+	window.toggleBit(4)
+	s, _ := window.state()
+	if string(s.Bytes[:2]) != "F\xc2" || s.Cursor != 1 || s.BitColumn != 1 {
+		t.Errorf("s.Bytes should be %q with the cursor at 1 and 1 but got %q at %d and %d",
+			"F\xc2", string(s.Bytes[:2]), s.Cursor, s.BitColumn)
+	}
+
+	window.startReplaceByte()
+	window.insertRune(mode.Replace, '1')
+	window.startReplace()
+	for _, ch := range "0000001101" {
+		window.insertRune(mode.Replace, ch)
+	}
+	s, _ = window.state()
+	if string(s.Bytes[:3]) != "F\x81\xa0" || s.Cursor != 2 || s.BitColumn != 3 {
+		t.Errorf("s.Bytes should be %q with the cursor at 2 and 3 but got %q at %d and %d",
+			"F\x81\xa0", string(s.Bytes[:3]), s.Cursor, s.BitColumn)
+	}
+
+	window.setOption("display", "hex")
+	if s, _ := window.state(); s.BitColumn != 0 {
+		t.Errorf("s.BitColumn should be 0 but got %d", s.BitColumn)
+	}
+}
+
+func TestWindowVisualLargeSelection(t *testing.T) {
+	n := 2*operatorChunk + 3
+	window, _ := newWindow(strings.NewReader(strings.Repeat("a", n)), "test", "test", make(chan struct{}))
+	window.setSize(16, 10)
+	window.cursorGotoOffset(1)
+	window.startVisual()
+	window.cursorGotoOffset(int64(n - 2))
+	window.operateVisual(event.Event{Type: event.Decrement})
+	_, bytes, err := window.readBytes(0, n)
+	if err != nil {
+		t.Fatalf("err should be nil but got: %v", err)
+	}
+	if expected := "a" + strings.Repeat("`", n-2) + "a"; string(bytes) != expected {
+		t.Errorf("the selection should be decremented")
+	}
+	if window.length != int64(n) || window.cursor != 1 {
+		t.Errorf("length and cursor should be %d and 1 but got %d and %d", n, window.length, window.cursor)
+	}
+}
+
+func TestWindowOperators(t *testing.T) {
+	r := strings.NewReader("Hello, world!\nHello, world!\n")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(8, 10)
+	window.registers = register.New()
+
+	window.operator = event.OperatorYank
+	window.operate(event.Event{Type: event.CursorNext, Count: 5})
+	if bs, _ := window.registers.Get('0'); string(bs) != "Hello" {
+		t.Errorf("register 0 should be %q but got %q", "Hello", string(bs))
+	}
+
+	window.operator, window.opCount = event.OperatorDelete, 2
+	window.operate(event.Event{Type: event.CursorNext, Count: 5})
+	s, _ := window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\nHello") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\nHello", string(s.Bytes))
+	}
+	if bs, _ := window.registers.Get('"'); string(bs) != "Hello, wor" {
+		t.Errorf("register \" should be %q but got %q", "Hello, wor", string(bs))
+	}
+
+	window.cursorNext(mode.Normal, 5)
+	window.operator = event.OperatorSwitchCase
+	window.operate(event.Event{Type: event.CursorEnd})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\nHELLo, world!\n") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\nHELLo, world!\n", string(s.Bytes))
+	}
+	if s.Cursor != 5 {
+		t.Errorf("s.Cursor should be %d but got %d", 5, s.Cursor)
+	}
+
+	window.operator = event.OperatorDelete
+	window.operate(event.Event{Type: event.FindByte, Arg: "6f"})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\nH, world!\n") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\nH, world!\n", string(s.Bytes))
+	}
+
+	window.operator = event.OperatorDelete
+	window.operate(event.Event{Type: event.FindByte, Arg: "7a"})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\nH, world!\n") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\nH, world!\n", string(s.Bytes))
+	}
+
+	window.operator = event.OperatorDelete
+	window.operate(event.Event{Type: event.OperatorDelete})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "orld!\n\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "orld!\n\x00", string(s.Bytes))
+	}
+	if s.Cursor != 0 || s.Length != 6 {
+		t.Errorf("s.Cursor and s.Length should be 0 and 6 but got %d and %d", s.Cursor, s.Length)
+	}
+
+	window.cursorNext(mode.Normal, 2)
+	window.operator = event.OperatorChange
+	if m := window.operate(event.Event{Type: event.CursorHead}); m != mode.Insert {
+		t.Errorf("operate should return %d but got %d", mode.Insert, m)
+	}
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\n\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\n\x00", string(s.Bytes))
+	}
+	if s.Cursor != 0 {
+		t.Errorf("s.Cursor should be %d but got %d", 0, s.Cursor)
+	}
+
+	window.exitInsert()
+	window.operator = event.OperatorChange
+	if m := window.operate(event.Event{Type: event.FindByte, Arg: "7a"}); m != mode.Normal {
+		t.Errorf("operate should return %d but got %d", mode.Normal, m)
+	}
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "ld!\n\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "ld!\n\x00", string(s.Bytes))
+	}
+}
+
+func TestWindowVisualOperations(t *testing.T) {
+	r := strings.NewReader("Hello, world!")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	window.registers = register.New()
+
+	window.cursorNext(mode.Normal, 7)
+	window.startVisual()
+	window.cursorNext(mode.Normal, 4)
+	window.operateVisual(event.Event{Type: event.OperatorYank})
+	if bs, _ := window.registers.Get('0'); string(bs) != "world" {
+		t.Errorf("register 0 should be %q but got %q", "world", string(bs))
+	}
+	if window.visualStart != -1 || window.cursor != 7 {
+		t.Errorf("visualStart and cursor should be -1 and 7 but got %d and %d", window.visualStart, window.cursor)
+	}
+	from, to, err := window.rangeToOffsets(&event.Range{From: event.VisualStart{}, To: event.VisualEnd{}})
+	if err != nil {
+		t.Errorf("err should be nil but got: %v", err)
+	}
+	if from != 7 || to != 11 {
+		t.Errorf("rangeToOffsets should return 7 and 11 but got %d and %d", from, to)
+	}
+
+	window.reselectVisual()
+	window.cursorPrev(2)
+	window.operateVisual(event.Event{Type: event.ReplaceSelection, Arg: "2a"})
+	s, _ := window.state()
+	if !strings.HasPrefix(string(s.Bytes), "Hello, ***ld!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "Hello, ***ld!\x00", string(s.Bytes))
+	}
+
+	window.reselectVisual()
+	window.operateVisual(event.Event{Type: event.Increment, Count: 2})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "Hello, ,,,ld!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "Hello, ,,,ld!\x00", string(s.Bytes))
+	}
+
+	window.cursorGotoOffset(0)
+	window.startVisual()
+	window.cursorNext(mode.Normal, 4)
+	window.switchVisualEnd()
+	window.operateVisual(event.Event{Type: event.OperatorSwitchCase})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "hELLO, ,,,ld!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "hELLO, ,,,ld!\x00", string(s.Bytes))
+	}
+
+	window.reselectVisual()
+	window.operateVisual(event.Event{Type: event.OperatorDelete})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), ", ,,,ld!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", ", ,,,ld!\x00", string(s.Bytes))
+	}
+
+	window.cursorNext(mode.Normal, 2)
+	window.startVisual()
+	window.cursorNext(mode.Normal, 2)
+	window.operateVisual(event.Event{Type: event.Put})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), ", hELLOld!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", ", hELLOld!\x00", string(s.Bytes))
+	}
+	if s.Cursor != 6 {
+		t.Errorf("s.Cursor should be %d but got %d", 6, s.Cursor)
+	}
+	if bs, _ := window.registers.Get('"'); string(bs) != ",,," {
+		t.Errorf("register \" should be %q but got %q", ",,,", string(bs))
+	}
+}
+
+func TestWindowVisualBlockOperations(t *testing.T) {
+	r := strings.NewReader("abcdefghijkl")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(4, 10)
+	window.registers = register.New()
+
+	window.cursorNext(mode.Normal, 2)
+	window.startVisualBlock()
+	window.cursorDown(2)
+	window.cursorPrev(1)
+	s, _ := window.state()
+	if !s.VisualBlock || s.VisualStart != 2 || s.Cursor != 9 {
+		t.Errorf("s.VisualBlock, s.VisualStart and s.Cursor should be true, 2 and 9 but got %v, %d and %d",
+			s.VisualBlock, s.VisualStart, s.Cursor)
+	}
+	window.operateVisual(event.Event{Type: event.OperatorYank})
+	if bs, _ := window.registers.Get('0'); string(bs) != "bcfgjk" {
+		t.Errorf("register 0 should be %q but got %q", "bcfgjk", string(bs))
+	}
+	if window.visualStart != -1 || window.visualBlock || window.cursor != 1 {
+		t.Errorf("visualStart, visualBlock and cursor should be -1, false and 1 but got %d, %v and %d",
+			window.visualStart, window.visualBlock, window.cursor)
+	}
+
+	window.reselectVisual()
+	window.operateVisual(event.Event{Type: event.ReplaceSelection, Arg: "2a"})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "a**de**hi**l\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "a**de**hi**l\x00", string(s.Bytes))
+	}
+
+	window.reselectVisual()
+	window.operateVisual(event.Event{Type: event.Increment, Count: 2})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "a,,de,,hi,,l\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "a,,de,,hi,,l\x00", string(s.Bytes))
+	}
+
+	window.reselectVisual()
+	if m := window.operateVisual(event.Event{Type: event.OperatorChange}); m != mode.Visual {
+		t.Errorf("change in blockwise visual mode should be ignored but got mode %v", m)
+	}
+	window.operateVisual(event.Event{Type: event.OperatorDelete})
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "a\x00\x00de\x00\x00hi\x00\x00l\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "a\x00\x00de\x00\x00hi\x00\x00l\x00", string(s.Bytes))
+	}
+	if s.Length != 12 {
+		t.Errorf("s.Length should be %d but got %d", 12, s.Length)
+	}
+	if bs, _ := window.registers.Get('1'); string(bs) != ",,,,,," {
+		t.Errorf("register 1 should be %q but got %q", ",,,,,,", string(bs))
+	}
+}
+
+func TestWindowPutBytes(t *testing.T) {
+	r := strings.NewReader("Hello, world!")
+	window, err := newWindow(r, "test", "test", make(chan struct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	window.setSize(16, 10)
+	window.registers = register.New()
+
+	window.cursorNext(mode.Normal, 7)
+	window.operator, window.opRegister = event.OperatorYank, 'a'
+	window.operate(event.Event{Type: event.CursorNext, Count: 5})
+	if bs, _ := window.registers.Get('a'); string(bs) != "world" {
+		t.Errorf("register a should be %q but got %q", "world", string(bs))
+	}
+	if bs, _ := window.registers.Get('0'); bs != nil {
+		t.Errorf("register 0 should be empty but got %q", string(bs))
+	}
+
+	window.cursorGotoOffset(4)
+	window.putBytes('a', 2, false)
+	s, _ := window.state()
+	if !strings.HasPrefix(string(s.Bytes), "Helloworldworld, world!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "Helloworldworld, world!\x00", string(s.Bytes))
+	}
+	if s.Cursor != 14 || s.Length != 23 {
+		t.Errorf("s.Cursor and s.Length should be 14 and 23 but got %d and %d", s.Cursor, s.Length)
+	}
+
+	window.cursorGotoOffset(0)
+	window.putBytes('"', 1, true)
+	s, _ = window.state()
+	if !strings.HasPrefix(string(s.Bytes), "worldHelloworldworld, world!\x00") {
+		t.Errorf("s.Bytes should start with %q but got %q", "worldHelloworldworld, world!\x00", string(s.Bytes))
+	}
+	if s.Cursor != 4 {
+		t.Errorf("s.Cursor should be %d but got %d", 4, s.Cursor)
+	}
+}